@@ -6,12 +6,24 @@ import (
 	"github.com/taras-by/tbot/store"
 	tlg "github.com/taras-by/tbot/telegram"
 	"log"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type options struct {
-	TelegramToken string
-	StorePath     string
+	TelegramToken  string
+	StorePath      string
+	Mode           string
+	Listen         string
+	PublicURL      string
+	WebhookSecret  string
+	Owners         string
+	BackupDir      string
+	BackupInterval time.Duration
+	BackupKeep     int
 }
 
 type app struct {
@@ -19,7 +31,7 @@ type app struct {
 	commit  string
 	date    string
 	version string
-	storage *store.Storage
+	storage store.Repository
 }
 
 func newApp() (a *app) {
@@ -34,7 +46,7 @@ func newApp() (a *app) {
 
 	var err error
 
-	a.storage, err = store.NewStorage(a.options.StorePath)
+	a.storage, err = store.Open(a.options.StorePath)
 	if err != nil {
 		log.Printf("storage creating error. Path: %s", a.options.StorePath)
 		log.Panic(err.Error())
@@ -52,22 +64,63 @@ func (a *app) makeBotService() (s *tlg.BotService) {
 	}
 	log.Printf("Authorized on account %s", bot.Self.UserName)
 
+	owners, err := parseOwners(a.options.Owners)
+	if err != nil {
+		log.Panic(err.Error())
+	}
+
 	handler := &tlg.MessageHandler{
 		Bot:     bot,
 		Storage: a.storage,
+		Owners:  owners,
 		Version: a.version,
 	}
 
 	service := tlg.BotService{
-		Bot:     bot,
 		Handler: handler,
 	}
+
+	switch a.options.Mode {
+	case modePolling:
+		service.Source = &tlg.Polling{Bot: bot}
+	case modeWebhook:
+		if a.options.PublicURL == "" {
+			log.Panic("Public URL is required in webhook mode")
+		}
+		if a.options.WebhookSecret == "" {
+			log.Panic("Webhook secret is required in webhook mode")
+		}
+		service.Source = &tlg.Webhook{
+			Bot:       bot,
+			Listen:    a.options.Listen,
+			PublicURL: a.options.PublicURL,
+			Secret:    a.options.WebhookSecret,
+		}
+	default:
+		log.Panicf("Unknown mode: %s", a.options.Mode)
+	}
 	service.Init()
 	return &service
 }
 
+// parseOwners reads the comma separated Telegram user ids
+func parseOwners(value string) (owners []int, err error) {
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("wrong owner id %q", field)
+		}
+		owners = append(owners, id)
+	}
+	return owners, nil
+}
+
 func (a *app) printVersion() {
-	fmt.Printf("Version: %s\nCommit: %s\nRuntime: %s %s/%s\nDate: %s\n",
+	fmt.Fprintf(os.Stderr, "Version: %s\nCommit: %s\nRuntime: %s %s/%s\nDate: %s\n",
 		a.version,
 		a.commit,
 		runtime.Version(),
@@ -78,5 +131,7 @@ func (a *app) printVersion() {
 }
 
 func (a *app) Close() () {
-	a.storage.Close()
+	if err := a.storage.Close(); err != nil {
+		log.Print(err)
+	}
 }
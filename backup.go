@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/taras-by/tbot/store"
+)
+
+const backupPattern = "tbot-*.db"
+
+// backup writes the consistent copy of the store to the file. The running bot
+// keeps the bolt file locked, so it is asked to write the backup through its socket.
+func backup(args []string) error {
+	if len(args) == 0 {
+		return errors.New("destination file is required")
+	}
+	dest, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+
+	var size int64
+	if socket, ok := backupSocket(); ok && socketExists(socket) {
+		size, err = store.RequestBackup(socket, dest)
+	} else {
+		a := newApp()
+		defer a.Close()
+		size, err = store.BackupFile(a.storage, dest)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backup of %d bytes is written to %s\n", size, dest)
+	return nil
+}
+
+// backupSocket returns the socket the running bot takes the backup requests on,
+// false if the store is not a bolt file
+func backupSocket() (string, bool) {
+	path, err := store.BoltPath(Opts.StorePath)
+	if err != nil {
+		return "", false
+	}
+	return store.BackupSocket(path), true
+}
+
+func socketExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// restore replaces the bolt store with the backup, the bot has to be stopped
+func restore(args []string) error {
+	if len(args) == 0 {
+		return errors.New("backup file is required")
+	}
+	path, err := store.BoltPath(Opts.StorePath)
+	if err != nil {
+		return err
+	}
+	old, err := store.RestoreBolt(path, args[0])
+	if err != nil {
+		return err
+	}
+	if old != "" {
+		fmt.Printf("The replaced store is kept in %s\n", old)
+	}
+	fmt.Printf("%s is restored from %s\n", path, args[0])
+	return nil
+}
+
+// backups writes the store to the directory on start, every interval
+// and on SIGUSR1, the oldest files beyond keep are removed. Without
+// the directory SIGUSR1 is only logged, so it does not stop the bot.
+type backups struct {
+	storage  store.Repository
+	dir      string
+	interval time.Duration
+	keep     int
+}
+
+func (b *backups) Run(stop <-chan struct{}, signals <-chan os.Signal) {
+	var ticks <-chan time.Time
+	if b.dir != "" {
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+		b.backup(time.Now())
+	}
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticks:
+			b.backup(now)
+		case <-signals:
+			if b.dir == "" {
+				log.Print("Backup directory is not set, SIGUSR1 is ignored")
+				continue
+			}
+			b.backup(time.Now())
+		}
+	}
+}
+
+// backupSignals registers SIGUSR1 before the bot starts, so the signal
+// never falls to its default action which terminates the process
+func backupSignals() (<-chan os.Signal, func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	return signals, func() { signal.Stop(signals) }
+}
+
+func (b *backups) backup(now time.Time) {
+	dest := filepath.Join(b.dir, "tbot-"+now.UTC().Format("20060102-150405")+".db")
+	if _, err := store.BackupFile(b.storage, dest); err != nil {
+		log.Printf("Backup failed: %v", err)
+		return
+	}
+	log.Printf("Backup is written to %s", dest)
+
+	// the names sort by time
+	files, err := filepath.Glob(filepath.Join(b.dir, backupPattern))
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	sort.Strings(files)
+	for len(files) > b.keep {
+		if err = os.Remove(files[0]); err != nil {
+			log.Print(err)
+		}
+		files = files[1:]
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/taras-by/tbot/store"
+)
+
+// export writes the lists of the chat to the file or to the stdout
+func export(chatId int64, format string, args []string) (err error) {
+	if chatId == 0 {
+		return errors.New("chat id is required")
+	}
+	if format != store.FormatJSON && format != store.FormatCSV {
+		return errors.Errorf("unknown format %s", format)
+	}
+
+	a := newApp()
+	defer a.Close()
+
+	data, err := store.ExportChat(a.storage, chatId)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if len(args) > 0 {
+		f, err := os.Create(args[0])
+		if err != nil {
+			return errors.Wrap(err, "failed to create file")
+		}
+		defer func() {
+			if e := f.Close(); err == nil {
+				err = e
+			}
+		}()
+		w = f
+	}
+
+	if format == store.FormatCSV {
+		err = store.WriteCSV(w, data)
+	} else {
+		err = store.WriteJSON(w, data)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Exported %d events and %d participants of chat %d", len(data.Events), len(data.Participants), chatId)
+	return nil
+}
+
+// importFile reads the lists exported by the bot, the format is taken
+// from the file extension unless it is set
+func importFile(chatId int64, format string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("file is required")
+	}
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(args[0])), ".")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return errors.Wrap(err, "failed to open file")
+	}
+	defer func() { _ = f.Close() }()
+
+	var data store.ChatExport
+	switch format {
+	case store.FormatCSV:
+		data, err = store.ReadCSV(f)
+	case store.FormatJSON:
+		data, err = store.ReadJSON(f)
+	default:
+		return errors.Errorf("unknown format %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	a := newApp()
+	defer a.Close()
+	if err = store.CheckSchema(a.storage); err != nil {
+		return err
+	}
+
+	count, err := store.ImportChat(a.storage, data, chatId)
+	if err != nil {
+		return err
+	}
+	if chatId == 0 {
+		chatId = data.ChatId
+	}
+	fmt.Printf("Imported %d events and %d participants to chat %d\n", len(data.Events), count, chatId)
+	return nil
+}
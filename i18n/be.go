@@ -0,0 +1,208 @@
+package i18n
+
+var be = map[string][]string{
+	// commands
+	"Parameter too long":                     {"Занадта доўгі параметр"},
+	"Wrong command":                          {"Невядомая каманда"},
+	"Wrong button":                           {"Невядомая кнопка"},
+	"Wrong parameter":                        {"Няправільны параметр"},
+	"Error":                                  {"Памылка"},
+	"Something went wrong, please try again": {"Нешта пайшло не так, паспрабуйце яшчэ раз"},
+	"Only administrators can use /%s":        {"Толькі адміністратары могуць выкарыстоўваць /%s"},
+	"Only administrators can add other participants":     {"Толькі адміністратары могуць дадаваць іншых удзельнікаў"},
+	"Only administrators can remove other participants":  {"Толькі адміністратары могуць выдаляць іншых удзельнікаў"},
+	"Only administrators can undo the changes of others": {"Толькі адміністратары могуць адмяняць чужыя змены"},
+	"Only administrators can change admin-only commands": {"Толькі адміністратары могуць змяняць каманды для адміністратараў"},
+	"Command /%s can't be admin-only":                    {"Каманду /%s нельга абмежаваць адміністратарамі"},
+	"*Admin-only commands:* none":                        {"*Каманды для адміністратараў:* няма"},
+	"*Admin-only commands:* %s":                          {"*Каманды для адміністратараў:* %s"},
+	"*Language:* %s":                                     {"*Мова:* %s"},
+	"Unknown language %s, use one of: %s":                {"Невядомая мова %s, выберыце адну з: %s"},
+
+	// participants
+	"*Added* %s":                      {"*Дададзена:* %s"},
+	"*Waitlisted* %s":                 {"*У спісе чакання:* %s"},
+	"*Maybe* %s":                      {"*Пад пытаннем:* %s"},
+	"*Declined* %s":                   {"*Адмова:* %s"},
+	"*Removed* %s":                    {"*Выдалена:* %s"},
+	"*Restored* %s":                   {"*Адноўлена:* %s"},
+	"*Restored* the list":             {"*Спіс адноўлены*"},
+	"*Promoted from the waitlist* %s": {"*Са спісу чакання ў спіс:* %s"},
+	"You have already answered: *%s*": {"Вы ўжо адказалі: *%s*"},
+	"User is already in the list of participants":   {"Гэты ўдзельнік ужо ў спісе"},
+	"%s is already in the list of participants":     {"%s ужо ў спісе"},
+	"%s (guest of %s)":                              {"%s (госць %s)"},
+	"Add the named guests one by one: /add +1 John": {"Дадавайце гасцей з імёнамі па адным: /add +1 Янка"},
+	"You can bring %d guests at most": {
+		"Можна прывесці не больш за %d госця",
+		"Можна прывесці не больш за %d гасцей",
+		"Можна прывесці не больш за %d гасцей",
+	},
+	"Only the one who brought the guest or an administrator can remove it": {
+		"Выдаліць госця можа толькі той, хто яго прывёў, або адміністратар",
+	},
+	"Fail. UserName as an number":   {"Памылка: імя не можа быць лікам"},
+	"You are not a participant yet": {"Вас яшчэ няма ў спісе"},
+	"going":                         {"іду"},
+	"maybe":                         {"магчыма"},
+	"declined":                      {"не іду"},
+
+	// list
+	"No participants":          {"Удзельнікаў няма"},
+	"Participants:\n":          {"Удзельнікі:\n"},
+	"Participants (%v/%v):\n":  {"Удзельнікі (%v/%v):\n"},
+	"Waitlist:\n":              {"Спіс чакання:\n"},
+	"Maybe:\n":                 {"Пад пытаннем:\n"},
+	"Declined:\n":              {"Адмовіліся:\n"},
+	"%d going":                 {"%d ідзе", "%d ідуць", "%d ідуць"},
+	"%d waitlisted":            {"%d у чарзе"},
+	"%d maybe":                 {"%d пад пытаннем"},
+	"%d declined":              {"%d адмова", "%d адмовы", "%d адмоў"},
+	"Join":                     {"Іду"},
+	"Maybe":                    {"Магчыма"},
+	"Leave":                    {"Выйсці"},
+	"*Unpinned*":               {"*Адмацавана*"},
+	"*Sent* to you in private": {"*Адпраўлена* вам у асабістыя паведамленні"},
+
+	// reset and undo
+	"Are you sure? All participants of *%s* will be removed": {"Вы ўпэўнены? Усе ўдзельнікі *%s* будуць выдалены"},
+	"Yes":       {"Так"},
+	"No":        {"Не"},
+	"Reset":     {"Спіс ачышчаны"},
+	"Cancelled": {"Адменена"},
+	"The question has expired, send /reset again":       {"Пытанне састарэла, адпраўце /reset яшчэ раз"},
+	"All participants was deleted, /undo restores them": {"Усе ўдзельнікі выдалены, /undo верне іх"},
+	"Nothing to undo": {"Няма чаго адмяняць"},
+
+	// history and stats
+	"No past events":                        {"Мінулых падзей няма"},
+	"Past events:\n":                        {"Мінулыя падзеі:\n"},
+	" *%s* %s, %d going\n":                  {" *%s* %s, %d ідзе\n", " *%s* %s, %d ідуць\n", " *%s* %s, %d ідуць\n"},
+	"History length must be from 1 to *%v*": {"Даўжыня гісторыі павінна быць ад 1 да *%v*"},
+	"*%s*, attendance of %d events:\n": {
+		"*%s*, наведвальнасць за %d падзею:\n",
+		"*%s*, наведвальнасць за %d падзеі:\n",
+		"*%s*, наведвальнасць за %d падзей:\n",
+	},
+	" *%v)* %s - %v, streak %v, best %v\n": {" *%v)* %s - %v, серыя %v, лепшая %v\n"},
+
+	// events
+	"Default":                {"Па змаўчанні"},
+	"Events:\n":              {"Падзеі:\n"},
+	" _(active)_":            {" _(актыўная)_"},
+	"*Created* %s":           {"*Створана:* %s"},
+	"*Switched* to %s":       {"*Пераключана на* %s"},
+	"Event \"%s\" not found": {"Падзея \"%s\" не знойдзена"},
+	"*Title updated*":        {"*Назва абноўлена*"},
+	"*Date updated*":         {"*Дата абноўлена*"},
+	"*Location updated*":     {"*Месца абноўлена*"},
+	"*Description updated*":  {"*Апісанне абноўлена*"},
+	"*Capacity updated*":     {"*Памер спіса абноўлены*"},
+	"*Schedule updated*":     {"*Расклад абноўлены*"},
+	"*Reminders updated*":    {"*Напаміны абноўлены*"},
+	"Wrong date, use format like: tomorrow 19:30, fri 7pm, 24.10 18:00 or %s": {
+		"Няправільная дата, выкарыстоўвайце фармат: заўтра 19:30, у пятніцу 19:00, 24.10 18:00 ці %s",
+	},
+	"*Date updated:* %s, send /when again if it is wrong": {"*Дата абноўлена:* %s, адпраўце /when яшчэ раз, калі яна няправільная"},
+	"Capacity must be from 1 to *%v*":                     {"Памер спіса павінен быць ад 1 да *%v*"},
+	"Wrong schedule, use format like: tue 19:00 2h":       {"Няправільны расклад, выкарыстоўвайце фармат: tue 19:00 2h"},
+	"Wrong reminders, use up to %d like: 24h 1h ping":     {"Няправільныя напаміны, пакажыце да %d, напрыклад: 24h 1h ping"},
+	"_When:_ %s\n":                         {"_Калі:_ %s\n"},
+	"_Where:_ %s\n":                        {"_Дзе:_ %s\n"},
+	"_Repeat:_ %s\n":                       {"_Паўтор:_ %s\n"},
+	"_Remind:_ %s before":                  {"_Нагадаць:_ за %s"},
+	", ping the rest":                      {", паклікаць астатніх"},
+	"%s %02d:%02d, reset %s after start":   {"%s %02d:%02d, скід праз %s пасля пачатку"},
+	"every Monday":                         {"кожны панядзелак"},
+	"every Tuesday":                        {"кожны аўторак"},
+	"every Wednesday":                      {"кожную сераду"},
+	"every Thursday":                       {"кожны чацвер"},
+	"every Friday":                         {"кожную пятніцу"},
+	"every Saturday":                       {"кожную суботу"},
+	"every Sunday":                         {"кожную нядзелю"},
+	"*%s* is open for %s":                  {"Адкрыты запіс на *%s*, %s"},
+	"*%s* starts in %s, %s":                {"*%s* пачнецца праз %s, %s"},
+	"*%s* is waiting for you:":             {"*%s* чакае вас:"},
+	"You have not answered yet:":           {"Вы яшчэ не адказалі:"},
+	"Everyone known has already signed up": {"Усе вядомыя ўдзельнікі чата ўжо запісаліся"},
+
+	// settings
+	"*Settings:*\n":      {"*Налады:*\n"},
+	"*Settings updated*": {"*Налады абноўлены*"},
+	"Unknown setting %s": {"Невядомая налада %s"},
+	"Only administrators can change the settings":        {"Толькі адміністратары могуць змяняць налады"},
+	"Wrong value %s, use on or off":                      {"Няправільнае значэнне %s, выкарыстоўвайце on ці off"},
+	"Unknown time zone %s, use a name like Europe/Minsk": {"Невядомы часавы пояс %s, выкарыстоўвайце назву накшталт Europe/Minsk"},
+	"Unknown format %s, use full or compact":             {"Невядомы фармат %s, выкарыстоўвайце full ці compact"},
+	"Guests are not allowed in this chat":                {"У гэтым чаце нельга дадаваць гасцей"},
+	"Guest quota must be from 1 to *%v*":                 {"Колькасць гасцей павінна быць ад 1 да *%v*"},
+	"guests a member can bring":                          {"колькі гасцей можа прывесці ўдзельнік"},
+	"default size of the lists":                          {"памер спісаў па змаўчанні"},
+	"time zone of the dates":                             {"часавы пояс дат"},
+	"adding participants by name":                        {"даданне ўдзельнікаў па імені"},
+	"only administrators can reset the list":             {"толькі адміністратары ачышчаюць спіс"},
+	"layout of the list":                                 {"выгляд спіса"},
+	"settings of the chat":                               {"налады чата"},
+	"not limited":                                        {"без абмежаванняў"},
+	"default":                                            {"па змаўчанні"},
+	"on":                                                 {"укл"},
+	"off":                                                {"выкл"},
+	"full":                                               {"поўны"},
+	"compact":                                            {"кароткі"},
+	"`/settings capacity 20` changes the setting, `/settings capacity -` restores the default": {
+		"`/settings capacity 20` змяняе наладу, `/settings capacity -` вяртае значэнне па змаўчанні",
+	},
+
+	// storage errors
+	"Participant %s not found":               {"Удзельнік %s не знойдзены"},
+	"Participant with number %d not found":   {"Удзельнік з нумарам %d не знойдзены"},
+	"Participant with number %s not found":   {"Удзельнік з нумарам %s не знойдзены"},
+	"Participant with name \"%s\" not found": {"Удзельнік з імем \"%s\" не знойдзены"},
+	"Participant with link %s not found":     {"Удзельнік %s не знойдзены"},
+	"Event %d not found":                     {"Падзея %d не знойдзена"},
+	"Archive %d not found":                   {"Архіў %d не знойдзены"},
+	"The list is full, maximum waitlist: %v": {"Спіс запоўнены, максімум у спісе чакання: %v"},
+
+	// help
+	"*Help:*\n":                                         {"*Дапамога:*\n"},
+	"*Examples:*\n":                                     {"*Прыклады:*\n"},
+	"_Version: %s_":                                     {"_Версія: %s_"},
+	"participants list":                                 {"спіс удзельнікаў"},
+	"pin the list message":                              {"замацаваць спіс"},
+	"add yourself or someone":                           {"запісаць сябе ці кагосьці"},
+	"remove yourself or someone":                        {"выдаліць сябе ці кагосьці"},
+	"answer maybe or not going":                         {"адказаць «магчыма» ці «не іду»"},
+	"remove all, the list is kept in the history":       {"выдаліць усіх, спіс захаваецца ў гісторыі"},
+	"restore the list after the last reset or removal":  {"вярнуць спіс пасля апошняй ачысткі ці выдалення"},
+	"past events":                                       {"мінулыя падзеі"},
+	"attendance of the event":                           {"наведвальнасць падзеі"},
+	"the list as a CSV file":                            {"спіс у файле CSV"},
+	"commands only administrators can use":              {"каманды толькі для адміністратараў"},
+	"language of the bot":                               {"мова бота"},
+	"events of the chat":                                {"падзеі чата"},
+	"event details":                                     {"падрабязнасці падзеі"},
+	"size of the list, the rest are waitlisted":         {"памер спіса, астатнія трапяць у спіс чакання"},
+	"reopen the list every week":                        {"адкрываць спіс кожны тыдзень"},
+	"remind before the start":                           {"нагадаць перад пачаткам"},
+	"turn to those who have not answered or said maybe": {"паклікаць тых, хто не адказаў або адказаў пад пытаннем"},
+	"help":                                  {"дапамога"},
+	"`/rm 3` removes the third participant": {"`/rm 3` выдаляе трэцяга ўдзельніка"},
+	"`/add +2` brings two guests, they leave the list together with you": {
+		"`/add +2` прыводзіць двух гасцей, яны пакінуць спіс разам з вамі",
+	},
+	"`/event switch 2` makes the second event active": {"`/event switch 2` робіць актыўнай другую падзею"},
+	"`-` clears the event detail":                     {"`-` ачышчае поле падзеі"},
+	"`/repeat tue 19:00 2h` resets the list every Tuesday 2 hours after 19:00": {
+		"`/repeat tue 19:00 2h` ачышчае спіс кожны аўторак праз 2 гадзіны пасля 19:00",
+	},
+	"`ping` in reminders turns to those who have not answered": {"`ping` у напамінах кліча тых, хто не адказаў"},
+	"`/admin reset rm title` lets only administrators reset the list, remove others and change the title": {
+		"`/admin reset rm title` дазваляе толькі адміністратарам ачышчаць спіс, выдаляць іншых і змяняць назву",
+	},
+	"`/lang ru` switches the bot to Russian": {"`/lang ru` пераключае бота на рускую"},
+
+	// dates
+	"Mon": {"Пн"}, "Tue": {"Аў"}, "Wed": {"Ср"}, "Thu": {"Чц"}, "Fri": {"Пт"}, "Sat": {"Сб"}, "Sun": {"Нд"},
+	"Jan": {"сту"}, "Feb": {"лют"}, "Mar": {"сак"}, "Apr": {"кра"}, "May": {"тра"}, "Jun": {"чэр"},
+	"Jul": {"ліп"}, "Aug": {"жні"}, "Sep": {"вер"}, "Oct": {"кас"}, "Nov": {"ліс"}, "Dec": {"сне"},
+}
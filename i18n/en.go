@@ -0,0 +1,11 @@
+package i18n
+
+// en keeps the plural forms only, the other messages are their keys
+var en = map[string][]string{
+	" *%s* %s, %d going\n": {" *%s* %s, %d going\n", " *%s* %s, %d going\n"},
+	"*%s*, attendance of %d events:\n": {
+		"*%s*, attendance of %d event:\n",
+		"*%s*, attendance of %d events:\n",
+	},
+	"You can bring %d guests at most": {"You can bring %d guest at most", "You can bring %d guests at most"},
+}
@@ -0,0 +1,163 @@
+// Package i18n translates the replies of the bot. The messages are looked up
+// by their English text, which is also the reply if there is no translation.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lang is the ISO 639-1 code of the language
+type Lang string
+
+const (
+	English    Lang = "en"
+	Russian    Lang = "ru"
+	Belarusian Lang = "be"
+)
+
+// Default is the language of the chats without a setting
+const Default = English
+
+// Languages are the supported languages
+var Languages = []Lang{English, Russian, Belarusian}
+
+// catalogs keep the forms of the messages: one for the plain message,
+// the plural forms in the order of the plural rule of the language
+var catalogs = map[Lang]map[string][]string{
+	English:    en,
+	Russian:    ru,
+	Belarusian: be,
+}
+
+var plurals = map[Lang]func(n int) int{
+	English:    pluralEnglish,
+	Russian:    pluralSlavic,
+	Belarusian: pluralSlavic,
+}
+
+var names = map[Lang]string{
+	English:    "English",
+	Russian:    "Русский",
+	Belarusian: "Беларуская",
+}
+
+// Parse returns the supported language of the code like "ru" or "be-BY"
+func Parse(code string) (Lang, bool) {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	for _, l := range Languages {
+		if string(l) == code {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// Name is the name of the language in itself
+func (l Lang) Name() string {
+	return names[l]
+}
+
+// Printer translates the messages to the language
+// and shows the dates in the location
+type Printer struct {
+	lang     Lang
+	location *time.Location
+}
+
+func New(lang Lang) Printer {
+	if _, ok := catalogs[lang]; !ok {
+		lang = Default
+	}
+	return Printer{lang: lang}
+}
+
+func (p Printer) Lang() Lang {
+	return p.lang
+}
+
+// In returns the printer showing the dates in the location
+func (p Printer) In(location *time.Location) Printer {
+	p.location = location
+	return p
+}
+
+// Location returns the location of the dates, the local one by default
+func (p Printer) Location() *time.Location {
+	if p.location == nil {
+		return time.Local
+	}
+	return p.location
+}
+
+// T translates the format and formats it with the args like fmt.Sprintf
+func (p Printer) T(format string, args ...interface{}) string {
+	return sprintf(p.form(format, 0), args)
+}
+
+// N translates the format in the plural form for n, e.g.
+// N("%d events", 1, 1) is "1 event" in English
+func (p Printer) N(format string, n int, args ...interface{}) string {
+	return sprintf(p.form(format, p.plural(n)), args)
+}
+
+// Date formats the time like "Mon, 02 Jan 2006 15:04" with the local
+// names of the weekday and the month
+func (p Printer) Date(t time.Time) string {
+	if p.location != nil {
+		t = t.In(p.location)
+	}
+	return fmt.Sprintf("%s, %02d %s %d %02d:%02d", p.T(t.Weekday().String()[:3]),
+		t.Day(), p.T(t.Month().String()[:3]), t.Year(), t.Hour(), t.Minute())
+}
+
+func (p Printer) form(format string, i int) string {
+	forms := catalogs[p.lang][format]
+	if len(forms) == 0 {
+		forms = en[format]
+	}
+	if len(forms) == 0 {
+		return format
+	}
+	if i >= len(forms) {
+		i = len(forms) - 1
+	}
+	return forms[i]
+}
+
+func (p Printer) plural(n int) int {
+	return plurals[p.lang](n)
+}
+
+func sprintf(format string, args []interface{}) string {
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// pluralEnglish chooses from one and other
+func pluralEnglish(n int) int {
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
+
+// pluralSlavic chooses from one (1, 21), few (2-4, 22-24) and many (0, 5-20, 25)
+func pluralSlavic(n int) int {
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,83 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPlural(t *testing.T) {
+	for n, want := range map[int]string{
+		0: "0 отказов", 1: "1 отказ", 2: "2 отказа", 5: "5 отказов", 11: "11 отказов",
+		12: "12 отказов", 21: "21 отказ", 22: "22 отказа", 111: "111 отказов",
+	} {
+		if got := New(Russian).N("%d declined", n, n); got != want {
+			t.Errorf("N(%d) = %q, want %q", n, got, want)
+		}
+	}
+	if got := New(English).N("*%s*, attendance of %d events:\n", 1, "Match", 1); got != "*Match*, attendance of 1 event:\n" {
+		t.Errorf("English singular %q", got)
+	}
+	if got := New(English).N("%d going", 3, 3); got != "3 going" {
+		t.Errorf("English message without forms %q", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for code, want := range map[string]Lang{"ru": Russian, "be-BY": Belarusian, "EN_us": English, "de": "", "": ""} {
+		if got, ok := Parse(code); got != want || ok != (want != "") {
+			t.Errorf("Parse(%q) = %q, %v", code, got, ok)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	p := New(Belarusian)
+	if got := p.T("*Added* %s", "@ann"); got != "*Дададзена:* @ann" {
+		t.Errorf("T = %q", got)
+	}
+	if got := p.T("No such message %d", 1); got != "No such message 1" {
+		t.Errorf("missing message %q", got)
+	}
+	date := time.Date(2020, time.May, 17, 19, 30, 0, 0, time.UTC)
+	if got := p.Date(date); got != "Нд, 17 тра 2020 19:30" {
+		t.Errorf("Date = %q", got)
+	}
+	if got := New("xx").Date(date); got != "Sun, 17 May 2020 19:30" {
+		t.Errorf("Date = %q", got)
+	}
+	if got := New(English).In(time.FixedZone("UTC+3", 3*60*60)).Date(date); got != "Sun, 17 May 2020 22:30" {
+		t.Errorf("Date in the location = %q", got)
+	}
+}
+
+var verb = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z]`)
+
+// TestCatalogs checks that the translations have the same messages,
+// the same verbs and the plural forms of the language
+func TestCatalogs(t *testing.T) {
+	for _, lang := range []Lang{Russian, Belarusian} {
+		for key, forms := range catalogs[lang] {
+			if _, ok := catalogs[Russian][key]; !ok {
+				t.Errorf("%s: %q is missing in ru", lang, key)
+			}
+			if _, ok := catalogs[Belarusian][key]; !ok {
+				t.Errorf("%s: %q is missing in be", lang, key)
+			}
+			if _, plural := en[key]; plural && len(forms) != 3 {
+				t.Errorf("%s: %q has %d plural forms", lang, key, len(forms))
+			}
+			want := verb.FindAllString(key, -1)
+			for _, form := range forms {
+				if got := verb.FindAllString(form, -1); len(got) != len(want) {
+					t.Errorf("%s: %q has verbs %v, want %v", lang, form, got, want)
+				}
+			}
+		}
+	}
+	for key, forms := range en {
+		if len(forms) != 2 {
+			t.Errorf("en: %q has %d plural forms", key, len(forms))
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package i18n
+
+var ru = map[string][]string{
+	// commands
+	"Parameter too long":                     {"Слишком длинный параметр"},
+	"Wrong command":                          {"Неизвестная команда"},
+	"Wrong button":                           {"Неизвестная кнопка"},
+	"Wrong parameter":                        {"Неверный параметр"},
+	"Error":                                  {"Ошибка"},
+	"Something went wrong, please try again": {"Что-то пошло не так, попробуйте ещё раз"},
+	"Only administrators can use /%s":        {"Только администраторы могут использовать /%s"},
+	"Only administrators can add other participants":     {"Только администраторы могут добавлять других участников"},
+	"Only administrators can remove other participants":  {"Только администраторы могут удалять других участников"},
+	"Only administrators can undo the changes of others": {"Только администраторы могут отменять чужие изменения"},
+	"Only administrators can change admin-only commands": {"Только администраторы могут менять команды для администраторов"},
+	"Command /%s can't be admin-only":                    {"Команду /%s нельзя ограничить администраторами"},
+	"*Admin-only commands:* none":                        {"*Команды для администраторов:* нет"},
+	"*Admin-only commands:* %s":                          {"*Команды для администраторов:* %s"},
+	"*Language:* %s":                                     {"*Язык:* %s"},
+	"Unknown language %s, use one of: %s":                {"Неизвестный язык %s, выберите один из: %s"},
+
+	// participants
+	"*Added* %s":                      {"*Добавлено:* %s"},
+	"*Waitlisted* %s":                 {"*В листе ожидания:* %s"},
+	"*Maybe* %s":                      {"*Под вопросом:* %s"},
+	"*Declined* %s":                   {"*Отказ:* %s"},
+	"*Removed* %s":                    {"*Удалено:* %s"},
+	"*Restored* %s":                   {"*Восстановлено:* %s"},
+	"*Restored* the list":             {"*Список восстановлен*"},
+	"*Promoted from the waitlist* %s": {"*Из листа ожидания в список:* %s"},
+	"You have already answered: *%s*": {"Вы уже ответили: *%s*"},
+	"User is already in the list of participants":   {"Этот участник уже в списке"},
+	"%s is already in the list of participants":     {"%s уже в списке"},
+	"%s (guest of %s)":                              {"%s (гость %s)"},
+	"Add the named guests one by one: /add +1 John": {"Добавляйте гостей с именами по одному: /add +1 Иван"},
+	"You can bring %d guests at most": {
+		"Можно привести не больше %d гостя",
+		"Можно привести не больше %d гостей",
+		"Можно привести не больше %d гостей",
+	},
+	"Only the one who brought the guest or an administrator can remove it": {
+		"Удалить гостя может только тот, кто его привёл, или администратор",
+	},
+	"Fail. UserName as an number":   {"Ошибка: имя не может быть числом"},
+	"You are not a participant yet": {"Вас ещё нет в списке"},
+	"going":                         {"иду"},
+	"maybe":                         {"возможно"},
+	"declined":                      {"не иду"},
+
+	// list
+	"No participants":          {"Участников нет"},
+	"Participants:\n":          {"Участники:\n"},
+	"Participants (%v/%v):\n":  {"Участники (%v/%v):\n"},
+	"Waitlist:\n":              {"Лист ожидания:\n"},
+	"Maybe:\n":                 {"Под вопросом:\n"},
+	"Declined:\n":              {"Отказались:\n"},
+	"%d going":                 {"%d идёт", "%d идут", "%d идут"},
+	"%d waitlisted":            {"%d в очереди"},
+	"%d maybe":                 {"%d под вопросом"},
+	"%d declined":              {"%d отказ", "%d отказа", "%d отказов"},
+	"Join":                     {"Иду"},
+	"Maybe":                    {"Возможно"},
+	"Leave":                    {"Выйти"},
+	"*Unpinned*":               {"*Откреплено*"},
+	"*Sent* to you in private": {"*Отправлено* вам в личные сообщения"},
+
+	// reset and undo
+	"Are you sure? All participants of *%s* will be removed": {"Вы уверены? Все участники *%s* будут удалены"},
+	"Yes":       {"Да"},
+	"No":        {"Нет"},
+	"Reset":     {"Список очищен"},
+	"Cancelled": {"Отменено"},
+	"The question has expired, send /reset again":       {"Вопрос устарел, отправьте /reset ещё раз"},
+	"All participants was deleted, /undo restores them": {"Все участники удалены, /undo вернёт их"},
+	"Nothing to undo": {"Нечего отменять"},
+
+	// history and stats
+	"No past events":                        {"Прошедших событий нет"},
+	"Past events:\n":                        {"Прошедшие события:\n"},
+	" *%s* %s, %d going\n":                  {" *%s* %s, %d идёт\n", " *%s* %s, %d идут\n", " *%s* %s, %d идут\n"},
+	"History length must be from 1 to *%v*": {"Длина истории должна быть от 1 до *%v*"},
+	"*%s*, attendance of %d events:\n": {
+		"*%s*, посещаемость за %d событие:\n",
+		"*%s*, посещаемость за %d события:\n",
+		"*%s*, посещаемость за %d событий:\n",
+	},
+	" *%v)* %s - %v, streak %v, best %v\n": {" *%v)* %s - %v, серия %v, лучшая %v\n"},
+
+	// events
+	"Default":                {"По умолчанию"},
+	"Events:\n":              {"События:\n"},
+	" _(active)_":            {" _(активное)_"},
+	"*Created* %s":           {"*Создано:* %s"},
+	"*Switched* to %s":       {"*Переключено на* %s"},
+	"Event \"%s\" not found": {"Событие \"%s\" не найдено"},
+	"*Title updated*":        {"*Название обновлено*"},
+	"*Date updated*":         {"*Дата обновлена*"},
+	"*Location updated*":     {"*Место обновлено*"},
+	"*Description updated*":  {"*Описание обновлено*"},
+	"*Capacity updated*":     {"*Размер списка обновлён*"},
+	"*Schedule updated*":     {"*Расписание обновлено*"},
+	"*Reminders updated*":    {"*Напоминания обновлены*"},
+	"Wrong date, use format like: tomorrow 19:30, fri 7pm, 24.10 18:00 or %s": {
+		"Неверная дата, используйте формат: завтра 19:30, в пятницу 19:00, 24.10 18:00 или %s",
+	},
+	"*Date updated:* %s, send /when again if it is wrong": {"*Дата обновлена:* %s, отправьте /when ещё раз, если она неверна"},
+	"Capacity must be from 1 to *%v*":                     {"Размер списка должен быть от 1 до *%v*"},
+	"Wrong schedule, use format like: tue 19:00 2h":       {"Неверное расписание, используйте формат: tue 19:00 2h"},
+	"Wrong reminders, use up to %d like: 24h 1h ping":     {"Неверные напоминания, укажите до %d, например: 24h 1h ping"},
+	"_When:_ %s\n":                         {"_Когда:_ %s\n"},
+	"_Where:_ %s\n":                        {"_Где:_ %s\n"},
+	"_Repeat:_ %s\n":                       {"_Повтор:_ %s\n"},
+	"_Remind:_ %s before":                  {"_Напомнить:_ за %s"},
+	", ping the rest":                      {", позвать остальных"},
+	"%s %02d:%02d, reset %s after start":   {"%s %02d:%02d, сброс через %s после начала"},
+	"every Monday":                         {"каждый понедельник"},
+	"every Tuesday":                        {"каждый вторник"},
+	"every Wednesday":                      {"каждую среду"},
+	"every Thursday":                       {"каждый четверг"},
+	"every Friday":                         {"каждую пятницу"},
+	"every Saturday":                       {"каждую субботу"},
+	"every Sunday":                         {"каждое воскресенье"},
+	"*%s* is open for %s":                  {"Открыта запись на *%s*, %s"},
+	"*%s* starts in %s, %s":                {"*%s* начнётся через %s, %s"},
+	"*%s* is waiting for you:":             {"*%s* ждёт вас:"},
+	"You have not answered yet:":           {"Вы ещё не ответили:"},
+	"Everyone known has already signed up": {"Все известные участники чата уже записались"},
+
+	// settings
+	"*Settings:*\n":      {"*Настройки:*\n"},
+	"*Settings updated*": {"*Настройки обновлены*"},
+	"Unknown setting %s": {"Неизвестная настройка %s"},
+	"Only administrators can change the settings":        {"Только администраторы могут менять настройки"},
+	"Wrong value %s, use on or off":                      {"Неверное значение %s, используйте on или off"},
+	"Unknown time zone %s, use a name like Europe/Minsk": {"Неизвестный часовой пояс %s, используйте название вроде Europe/Minsk"},
+	"Unknown format %s, use full or compact":             {"Неизвестный формат %s, используйте full или compact"},
+	"Guests are not allowed in this chat":                {"В этом чате нельзя добавлять гостей"},
+	"Guest quota must be from 1 to *%v*":                 {"Число гостей должно быть от 1 до *%v*"},
+	"guests a member can bring":                          {"сколько гостей может привести участник"},
+	"default size of the lists":                          {"размер списков по умолчанию"},
+	"time zone of the dates":                             {"часовой пояс дат"},
+	"adding participants by name":                        {"добавление участников по имени"},
+	"only administrators can reset the list":             {"только администраторы очищают список"},
+	"layout of the list":                                 {"вид списка"},
+	"settings of the chat":                               {"настройки чата"},
+	"not limited":                                        {"без ограничений"},
+	"default":                                            {"по умолчанию"},
+	"on":                                                 {"вкл"},
+	"off":                                                {"выкл"},
+	"full":                                               {"полный"},
+	"compact":                                            {"краткий"},
+	"`/settings capacity 20` changes the setting, `/settings capacity -` restores the default": {
+		"`/settings capacity 20` меняет настройку, `/settings capacity -` возвращает значение по умолчанию",
+	},
+
+	// storage errors
+	"Participant %s not found":               {"Участник %s не найден"},
+	"Participant with number %d not found":   {"Участник с номером %d не найден"},
+	"Participant with number %s not found":   {"Участник с номером %s не найден"},
+	"Participant with name \"%s\" not found": {"Участник с именем \"%s\" не найден"},
+	"Participant with link %s not found":     {"Участник %s не найден"},
+	"Event %d not found":                     {"Событие %d не найдено"},
+	"Archive %d not found":                   {"Архив %d не найден"},
+	"The list is full, maximum waitlist: %v": {"Список заполнен, максимум в листе ожидания: %v"},
+
+	// help
+	"*Help:*\n":                                         {"*Помощь:*\n"},
+	"*Examples:*\n":                                     {"*Примеры:*\n"},
+	"_Version: %s_":                                     {"_Версия: %s_"},
+	"participants list":                                 {"список участников"},
+	"pin the list message":                              {"закрепить список"},
+	"add yourself or someone":                           {"записать себя или кого-то"},
+	"remove yourself or someone":                        {"удалить себя или кого-то"},
+	"answer maybe or not going":                         {"ответить «возможно» или «не иду»"},
+	"remove all, the list is kept in the history":       {"удалить всех, список сохранится в истории"},
+	"restore the list after the last reset or removal":  {"вернуть список после последней очистки или удаления"},
+	"past events":                                       {"прошедшие события"},
+	"attendance of the event":                           {"посещаемость события"},
+	"the list as a CSV file":                            {"список в файле CSV"},
+	"commands only administrators can use":              {"команды только для администраторов"},
+	"language of the bot":                               {"язык бота"},
+	"events of the chat":                                {"события чата"},
+	"event details":                                     {"подробности события"},
+	"size of the list, the rest are waitlisted":         {"размер списка, остальные попадут в лист ожидания"},
+	"reopen the list every week":                        {"открывать список каждую неделю"},
+	"remind before the start":                           {"напомнить перед началом"},
+	"turn to those who have not answered or said maybe": {"позвать тех, кто не ответил или ответил под вопросом"},
+	"help":                                  {"помощь"},
+	"`/rm 3` removes the third participant": {"`/rm 3` удаляет третьего участника"},
+	"`/add +2` brings two guests, they leave the list together with you": {
+		"`/add +2` приводит двух гостей, они покинут список вместе с вами",
+	},
+	"`/event switch 2` makes the second event active": {"`/event switch 2` делает активным второе событие"},
+	"`-` clears the event detail":                     {"`-` очищает поле события"},
+	"`/repeat tue 19:00 2h` resets the list every Tuesday 2 hours after 19:00": {
+		"`/repeat tue 19:00 2h` очищает список каждый вторник через 2 часа после 19:00",
+	},
+	"`ping` in reminders turns to those who have not answered": {"`ping` в напоминаниях зовёт тех, кто не ответил"},
+	"`/admin reset rm title` lets only administrators reset the list, remove others and change the title": {
+		"`/admin reset rm title` разрешает только администраторам очищать список, удалять других и менять название",
+	},
+	"`/lang ru` switches the bot to Russian": {"`/lang ru` переключает бота на русский"},
+
+	// dates
+	"Mon": {"Пн"}, "Tue": {"Вт"}, "Wed": {"Ср"}, "Thu": {"Чт"}, "Fri": {"Пт"}, "Sat": {"Сб"}, "Sun": {"Вс"},
+	"Jan": {"янв"}, "Feb": {"фев"}, "Mar": {"мар"}, "Apr": {"апр"}, "May": {"мая"}, "Jun": {"июн"},
+	"Jul": {"июл"}, "Aug": {"авг"}, "Sep": {"сен"}, "Oct": {"окт"}, "Nov": {"ноя"}, "Dec": {"дек"},
+}
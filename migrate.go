@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/taras-by/tbot/store"
+)
+
+func migrate(dryRun bool) error {
+	a := newApp()
+	defer a.Close()
+
+	m, ok := a.storage.(store.Migrator)
+	if !ok {
+		fmt.Println("The storage has no migrations")
+		return nil
+	}
+
+	version, err := m.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	applied, err := m.Migrate(dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range applied {
+		fmt.Printf("Migration %d: %s, %d changed\n", r.Version, r.Name, r.Changed)
+	}
+	switch {
+	case len(applied) == 0:
+		fmt.Printf("Schema version %d is up to date\n", version)
+	case dryRun:
+		fmt.Printf("Dry run, schema version %d is kept\n", version)
+	default:
+		fmt.Printf("Schema version: %d -> %d\n", version, store.SchemaVersion)
+	}
+	return nil
+}
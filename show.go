@@ -2,15 +2,49 @@ package main
 
 import (
 	"fmt"
+	"time"
 )
 
 func show() (err error) {
 	a := newApp()
 	defer a.Close()
 
-	for _, p := range a.storage.FindAll() {
+	events, err := a.storage.FindAllEvents()
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		fmt.Printf("Event: %d/%d %q", e.ChatId, e.Id, e.Name())
+		if !e.Start.IsZero() {
+			fmt.Printf(", when: %s", e.Start.Format(time.RFC3339))
+		}
+		if e.Location != "" {
+			fmt.Printf(", where: %q", e.Location)
+		}
+		if e.Description != "" {
+			fmt.Printf(", about: %q", e.Description)
+		}
+		fmt.Println()
+	}
+
+	participants, err := a.storage.FindAll()
+	if err != nil {
+		return err
+	}
+	for _, p := range participants {
 		fmt.Printf("Participant: %v\n", p)
 	}
 
+	archive, err := a.storage.FindAllArchive()
+	if err != nil {
+		return err
+	}
+	for _, r := range archive {
+		fmt.Printf("Archive: %d/%d %q at %s\n", r.Event.ChatId, r.Id, r.Event.Name(), r.Time.Format(time.RFC3339))
+		for _, p := range r.Participants {
+			fmt.Printf("  Participant: %v\n", p)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,13 @@
+package store
+
+import (
+	"time"
+)
+
+// Archive is a finished list of the event
+type Archive struct {
+	Id           int
+	Event        Event
+	Participants []Participant
+	Time         time.Time
+}
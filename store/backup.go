@@ -0,0 +1,227 @@
+package store
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+// Backuper is implemented by the storages that can write
+// a consistent copy of themselves while they are in use
+type Backuper interface {
+	Backup(w io.Writer) (int64, error)
+}
+
+// Backup writes the snapshot of the database in a read transaction,
+// so the bot keeps answering while it is copied
+func (s *BoltStorage) Backup(w io.Writer) (size int64, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		size, err = tx.WriteTo(w)
+		return err
+	})
+	return size, errors.Wrap(err, "failed to write backup")
+}
+
+// backupRequestTimeout limits the backup requested through the socket
+const backupRequestTimeout = 5 * time.Minute
+
+// BackupFile writes the backup to the temporary file next to dest and renames
+// it, so dest is never left half written. The missing directory is created.
+func BackupFile(r Repository, dest string) (int64, error) {
+	b, ok := r.(Backuper)
+	if !ok {
+		return 0, errors.New("the storage does not support backup")
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return 0, errors.Wrap(err, "failed to create backup directory")
+	}
+	return writeFile(dest, func(w io.Writer) (int64, error) {
+		return b.Backup(w)
+	})
+}
+
+// ServeBackups writes the backups requested by RequestBackup through the unix
+// socket, so they can be made while the storage is locked by the running bot.
+// The request is the destination path, the answer is "OK <size>" or "ERR <error>".
+// Closing the listener stops serving.
+func ServeBackups(r Repository, socket string) (net.Listener, error) {
+	// the socket is left by the bot which was killed
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to remove old backup socket")
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen backup socket")
+	}
+	if err = os.Chmod(socket, 0600); err != nil {
+		_ = l.Close()
+		return nil, errors.Wrap(err, "failed to set backup socket mode")
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveBackup(r, conn)
+		}
+	}()
+	return l, nil
+}
+
+func serveBackup(r Repository, conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(backupRequestTimeout))
+
+	dest, err := bufio.NewReader(conn).ReadString('\n')
+	if err == nil {
+		var size int64
+		if size, err = BackupFile(r, strings.TrimSpace(dest)); err == nil {
+			_, _ = fmt.Fprintf(conn, "OK %d\n", size)
+			return
+		}
+	}
+	_, _ = fmt.Fprintf(conn, "ERR %s\n", strings.Replace(err.Error(), "\n", " ", -1))
+}
+
+// RequestBackup asks the bot serving the socket to write the backup to dest,
+// which should be absolute as the bot has its own working directory
+func RequestBackup(socket string, dest string) (int64, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to connect backup socket")
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(backupRequestTimeout))
+
+	if _, err = fmt.Fprintf(conn, "%s\n", dest); err != nil {
+		return 0, errors.Wrap(err, "failed to request backup")
+	}
+	answer, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read backup answer")
+	}
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(answer, "OK ") {
+		size, err := strconv.ParseInt(strings.TrimPrefix(answer, "OK "), 10, 64)
+		return size, errors.Wrapf(err, "wrong backup answer %s", answer)
+	}
+	return 0, errors.New(strings.TrimPrefix(answer, "ERR "))
+}
+
+// BackupSocket returns the socket of the bot running on the bolt file
+func BackupSocket(boltPath string) string {
+	return boltPath + ".sock"
+}
+
+// ValidateBolt checks that the file is a readable bolt database
+// of the bot with a schema this build can migrate
+func ValidateBolt(path string) error {
+	// bolt.Open creates the missing file
+	if _, err := os.Stat(path); err != nil {
+		return errors.Wrap(err, "failed to open backup")
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: lockTimeout, ReadOnly: true})
+	if err != nil {
+		return errors.Wrapf(err, "%s is not a bolt database", path)
+	}
+	defer func() { _ = db.Close() }()
+
+	return db.View(func(tx *bolt.Tx) error {
+		for err := range tx.Check() {
+			return errors.Wrapf(err, "%s is damaged", path)
+		}
+		if tx.Bucket([]byte(chatsBucketName)) == nil {
+			return errors.Errorf("%s has no chats", path)
+		}
+		version, err := schemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		if version > SchemaVersion {
+			return errors.Errorf("schema version %d of %s is newer than %d", version, path, SchemaVersion)
+		}
+		return nil
+	})
+}
+
+// RestoreBolt replaces the bolt file at path with the validated backup. The
+// replaced file is kept with the .old suffix. The bot has to be stopped.
+func RestoreBolt(path, src string) (old string, err error) {
+	if err = ValidateBolt(src); err != nil {
+		return "", err
+	}
+
+	if _, err = os.Stat(path); err == nil {
+		db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: lockTimeout})
+		if err == bolt.ErrTimeout {
+			return "", errors.Errorf("%s is in use, stop the bot first", path)
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to open %s", path)
+		}
+		_ = db.Close()
+		old = path + ".old"
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open backup")
+	}
+	defer func() { _ = f.Close() }()
+
+	tmp := path + ".restore"
+	if _, err = writeFile(tmp, func(w io.Writer) (int64, error) {
+		return io.Copy(w, f)
+	}); err != nil {
+		return "", err
+	}
+	if old != "" {
+		if err = os.Rename(path, old); err != nil {
+			_ = os.Remove(tmp)
+			return "", errors.Wrap(err, "failed to keep the replaced store")
+		}
+	}
+	return old, errors.Wrap(os.Rename(tmp, path), "failed to replace the store")
+}
+
+// writeFile writes and syncs the temporary file, then renames it to path
+func writeFile(path string, write func(w io.Writer) (int64, error)) (size int64, err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create file")
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if size, err = write(f); err != nil {
+		return 0, err
+	}
+	if err = f.Chmod(0600); err != nil {
+		return 0, errors.Wrap(err, "failed to set file mode")
+	}
+	if err = f.Sync(); err != nil {
+		return 0, errors.Wrap(err, "failed to sync file")
+	}
+	if err = f.Close(); err != nil {
+		return 0, errors.Wrap(err, "failed to close file")
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return 0, errors.Wrap(err, "failed to rename file")
+	}
+	return size, nil
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bolt.db")
+	s, err := NewBoltStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smith := Participant{User: User{Id: "1", UserName: "smith", Type: UserTelegram}, ChatId: 1}
+	if _, err = s.Create(smith); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := filepath.Join(dir, "backup.db")
+	if size, err := BackupFile(s, backup); err != nil || size == 0 {
+		t.Fatalf("backup of %d bytes: %v", size, err)
+	}
+	if err = ValidateBolt(backup); err != nil {
+		t.Error(err)
+	}
+	if _, err = RestoreBolt(path, backup); err == nil {
+		t.Error("store in use is restored")
+	}
+
+	_ = s.Delete(smith)
+	_ = s.Close()
+
+	old, err := RestoreBolt(path, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old != path+".old" {
+		t.Errorf("replaced store is kept in %q", old)
+	}
+	s, err = NewBoltStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+	if _, err = s.Find(smith); err != nil {
+		t.Errorf("participant is not restored: %v", err)
+	}
+}
+
+func TestValidateBolt(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.db")
+	if err := os.WriteFile(broken, []byte("not a database"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{broken, filepath.Join(dir, "missing.db")} {
+		if err := ValidateBolt(path); err == nil {
+			t.Errorf("%s is valid", path)
+		}
+		if _, err := RestoreBolt(filepath.Join(dir, "bolt.db"), path); err == nil {
+			t.Errorf("%s is restored", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.db")); err == nil {
+		t.Error("validation created the file")
+	}
+	if _, err := BackupFile(NewMemoryStorage(), filepath.Join(dir, "memory.db")); err == nil {
+		t.Error("memory storage is backed up")
+	}
+}
+
+func TestServeBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bolt.db")
+	s, err := NewBoltStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	l, err := ServeBackups(s, BackupSocket(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+
+	// the storage stays locked, the backup is written by its owner
+	backup := filepath.Join(dir, "backup", "manual.db")
+	if size, err := RequestBackup(BackupSocket(path), backup); err != nil || size == 0 {
+		t.Fatalf("backup of %d bytes: %v", size, err)
+	}
+	if err = ValidateBolt(backup); err != nil {
+		t.Error(err)
+	}
+	if _, err = RequestBackup(BackupSocket(path), filepath.Join(path, "backup.db")); err == nil {
+		t.Error("backup into the file is written")
+	}
+}
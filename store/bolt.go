@@ -0,0 +1,798 @@
+package store
+
+import (
+	"encoding/json"
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	chatsBucketName    = "chats"
+	eventsBucketName   = "events"
+	membersBucketName  = "members"
+	archiveBucketName  = "archive"
+	jobsBucketName     = "jobs"
+	settingsBucketName = "settings"
+	journalBucketName  = "journal"
+)
+
+// lockTimeout limits the wait for the file locked by the running bot
+const lockTimeout = time.Second
+
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+func NewBoltStorage(storePath string) (*BoltStorage, error) {
+
+	bdb, err := bolt.Open(storePath, 0600, &bolt.Options{Timeout: lockTimeout})
+
+	if err == bolt.ErrTimeout {
+		return nil, errors.Errorf("%s is locked by another process", storePath)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make boltdb for %s", storePath)
+	}
+
+	err = bdb.Update(func(tx *bolt.Tx) error {
+		// the new database has the current layout, the old one without
+		// the version is migrated from the start
+		if tx.Bucket([]byte(chatsBucketName)) == nil {
+			if err := setSchemaVersion(tx, SchemaVersion); err != nil {
+				return err
+			}
+		}
+		for _, name := range []string{chatsBucketName, membersBucketName, archiveBucketName, jobsBucketName, settingsBucketName, journalBucketName} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return errors.Wrapf(err, "failed to create bucket %s", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = bdb.Close()
+		return nil, err
+	}
+	log.Printf("Storage opened in %s", storePath)
+
+	return &BoltStorage{
+		db: bdb,
+	}, nil
+}
+
+func (s *BoltStorage) Close() error {
+	if err := s.db.Close(); err != nil {
+		return errors.Wrap(err, "Failed to close storage")
+	}
+	log.Print("Storage closed")
+	return nil
+}
+
+func (s *BoltStorage) Create(participant Participant) (Participant, error) {
+	err := s.db.Update(func(tx *bolt.Tx) (err error) {
+		var chatBkt *bolt.Bucket
+
+		if chatBkt, err = s.makeChatBucket(tx, participant.ChatId); err != nil {
+			return err
+		}
+
+		if err = s.save(chatBkt, participant.Id(), participant); err != nil {
+			return errors.Wrapf(err, "failed to put key %s to chat bucket %v", participant.Id(), participant.ChatId)
+		}
+
+		return nil
+	})
+
+	return participant, errors.Wrapf(err, "Failed to save participant")
+}
+
+func (s *BoltStorage) Delete(participant Participant) error {
+	err := s.db.Update(func(tx *bolt.Tx) (err error) {
+		var chatBkt *bolt.Bucket
+
+		if chatBkt, err = s.makeChatBucket(tx, participant.ChatId); err != nil {
+			return err
+		}
+
+		if err = chatBkt.Delete([]byte(participant.Id())); err != nil {
+			return errors.Wrapf(err, "failed to delete key %s from chat bucket %v", participant.Id(), participant.ChatId)
+		}
+
+		return nil
+	})
+	return errors.Wrapf(err, "Failed to delete participant")
+}
+
+func (s *BoltStorage) DeleteByEvent(event Event) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		chatBkt := s.findChatBucket(tx, event.ChatId)
+		if chatBkt == nil {
+			return nil
+		}
+		participants, e := s.participants(chatBkt, event.Id)
+		if e != nil {
+			return e
+		}
+		for _, p := range participants {
+			if e = chatBkt.Delete([]byte(p.Id())); e != nil {
+				return e
+			}
+		}
+		return nil
+	})
+	return errors.Wrapf(err, "Failed to delete participants")
+}
+
+func (s *BoltStorage) Find(p Participant) (participant Participant, err error) {
+	err = s.db.View(func(tx *bolt.Tx) (err error) {
+		var value []byte
+		if chatBkt := s.findChatBucket(tx, p.ChatId); chatBkt != nil {
+			value = chatBkt.Get([]byte(p.Id()))
+		}
+		if value == nil {
+			return NewError(ErrNotFound, "Participant %s not found", p.Id())
+		}
+
+		if err := json.Unmarshal(value, &participant); err != nil {
+			return errors.Wrap(err, "failed to unmarshal")
+		}
+
+		return nil
+	})
+	return participant, err
+}
+
+func (s *BoltStorage) FindByNumber(number int, event Event) (Participant, error) {
+	participants, err := s.FindByEvent(event)
+	if err != nil {
+		return Participant{}, err
+	}
+	return findByNumber(participants, number)
+}
+
+func (s *BoltStorage) FindByName(name string, event Event) (Participant, error) {
+	participants, err := s.FindByEvent(event)
+	if err != nil {
+		return Participant{}, err
+	}
+	return findByName(participants, name)
+}
+
+func (s *BoltStorage) FindByLink(name string, event Event) (Participant, error) {
+	participants, err := s.FindByEvent(event)
+	if err != nil {
+		return Participant{}, err
+	}
+	return findByLink(participants, name)
+}
+
+func (s *BoltStorage) FindAll() (participants []Participant, err error) {
+	values, err := s.list(chatsBucketName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to find participants")
+	}
+	participants = []Participant{}
+	for _, v := range values {
+		participant := Participant{}
+		if e := json.Unmarshal(v, &participant); e != nil {
+			return nil, errors.Wrap(e, "failed to unmarshal")
+		}
+		participants = append(participants, participant)
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].Time.After(participants[j].Time)
+	})
+	return participants, nil
+}
+
+func (s *BoltStorage) FindByEvent(event Event) (participants []Participant, err error) {
+
+	err = s.db.View(func(tx *bolt.Tx) (e error) {
+
+		bucket := s.findChatBucket(tx, event.ChatId)
+		if bucket == nil {
+			return nil
+		}
+		participants, e = s.participants(bucket, event.Id)
+		return e
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to find participants")
+	}
+	sortByStatus(participants)
+	return participants, nil
+}
+
+func (s *BoltStorage) CountByEvent(event Event) (int, error) {
+	participants, err := s.FindByEvent(event)
+	return len(participants), err
+}
+
+// AddIfAbsent adds the participant unless the same person is already
+// in the list. The capacity is checked in the same transaction.
+func (s *BoltStorage) AddIfAbsent(participant Participant, capacity int) (stored Participant, result AddResult, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		chatBkt, err := s.makeChatBucket(tx, participant.ChatId)
+		if err != nil {
+			return err
+		}
+		participants, err := s.participants(chatBkt, participant.EventId)
+		if err != nil {
+			return err
+		}
+
+		var replaced *Participant
+		stored, replaced, result, err = addIfAbsent(participants, participant, capacity)
+		if err != nil || result == Duplicate {
+			return err
+		}
+		if replaced != nil && replaced.Id() != stored.Id() {
+			if err = chatBkt.Delete([]byte(replaced.Id())); err != nil {
+				return err
+			}
+		}
+		return s.save(chatBkt, stored.Id(), stored)
+	})
+	return stored, result, errors.Wrapf(err, "Failed to add participant")
+}
+
+func (s *BoltStorage) list(bucketName string) (values [][]byte, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		return b.ForEach(func(k, v []byte) error {
+			return b.Bucket(k).ForEach(func(k, v []byte) error {
+				if v == nil { // skip nested buckets
+					return nil
+				}
+				values = append(values, v)
+				return nil
+			})
+		})
+	})
+	return values, err
+}
+
+func (s *BoltStorage) save(bkt *bolt.Bucket, key string, value interface{}) (err error) {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal %s", key)
+	}
+	err = bkt.Put([]byte(key), jsonData)
+	if err != nil {
+		return errors.Wrapf(err, "failed to put %s", key)
+	}
+	return nil
+}
+
+// findChatBucket returns nil if nothing has been saved for the chat yet
+func (s *BoltStorage) findChatBucket(tx *bolt.Tx, chatId int64) *bolt.Bucket {
+	return tx.Bucket([]byte(chatsBucketName)).Bucket([]byte(strconv.FormatInt(chatId, 10)))
+}
+
+func (s *BoltStorage) makeChatBucket(tx *bolt.Tx, chatId int64) (*bolt.Bucket, error) {
+	chatsBkt := tx.Bucket([]byte(chatsBucketName))
+	if chatsBkt == nil {
+		return nil, errors.Errorf("no bucket %s", chatsBucketName)
+	}
+	chatBucketName := strconv.FormatInt(chatId, 10)
+	res, err := chatsBkt.CreateBucketIfNotExists([]byte(chatBucketName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "no bucket %d in store", chatId)
+	}
+	return res, nil
+}
+
+func (s *BoltStorage) participants(chatBkt *bolt.Bucket, eventId int) (participants []Participant, err error) {
+	err = chatBkt.ForEach(func(k, v []byte) error {
+		if v == nil { // skip nested buckets
+			return nil
+		}
+		participant := Participant{}
+		if e := json.Unmarshal(v, &participant); e != nil {
+			return errors.Wrap(e, "failed to unmarshal")
+		}
+		if participant.EventId == eventId {
+			participants = append(participants, participant)
+		}
+		return nil
+	})
+	return participants, err
+}
+
+func (s *BoltStorage) CreateEvent(chatId int64, title string) (event Event, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) (err error) {
+		var eventsBkt *bolt.Bucket
+
+		if eventsBkt, err = s.makeEventsBucket(tx, chatId); err != nil {
+			return err
+		}
+
+		if err = s.deactivateEvents(eventsBkt); err != nil {
+			return err
+		}
+
+		id, err := eventsBkt.NextSequence()
+		if err != nil {
+			return errors.Wrap(err, "failed to get next event id")
+		}
+
+		event = Event{
+			Id:     int(id),
+			ChatId: chatId,
+			Title:  title,
+			Active: true,
+			Time:   time.Now(),
+		}
+
+		return s.save(eventsBkt, strconv.Itoa(event.Id), event)
+	})
+	return event, errors.Wrapf(err, "Failed to create event")
+}
+
+func (s *BoltStorage) FindEvents(chatId int64) (events []Event, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		chatBkt := s.findChatBucket(tx, chatId)
+		if chatBkt == nil {
+			return nil
+		}
+		eventsBkt := chatBkt.Bucket([]byte(eventsBucketName))
+		if eventsBkt == nil {
+			return nil
+		}
+		return eventsBkt.ForEach(func(k, v []byte) error {
+			event := Event{}
+			if e := json.Unmarshal(v, &event); e != nil {
+				return errors.Wrap(e, "failed to unmarshal")
+			}
+			events = append(events, event)
+			return nil
+		})
+	})
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id < events[j].Id
+	})
+	return events, err
+}
+
+func (s *BoltStorage) FindAllEvents() (events []Event, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		chatsBkt := tx.Bucket([]byte(chatsBucketName))
+		return chatsBkt.ForEach(func(k, v []byte) error {
+			eventsBkt := chatsBkt.Bucket(k).Bucket([]byte(eventsBucketName))
+			if eventsBkt == nil {
+				return nil
+			}
+			return eventsBkt.ForEach(func(k, v []byte) error {
+				event := Event{}
+				if e := json.Unmarshal(v, &event); e != nil {
+					return errors.Wrap(e, "failed to unmarshal")
+				}
+				events = append(events, event)
+				return nil
+			})
+		})
+	})
+	return events, err
+}
+
+func (s *BoltStorage) FindEvent(chatId int64, eventId int) (event Event, err error) {
+	event = Event{ChatId: chatId}
+	err = s.db.View(func(tx *bolt.Tx) error {
+		if chatBkt := s.findChatBucket(tx, chatId); chatBkt != nil {
+			if eventsBkt := chatBkt.Bucket([]byte(eventsBucketName)); eventsBkt != nil {
+				if value := eventsBkt.Get([]byte(strconv.Itoa(eventId))); value != nil {
+					return errors.Wrap(json.Unmarshal(value, &event), "failed to unmarshal")
+				}
+			}
+		}
+		if eventId != 0 {
+			return NewError(ErrNotFound, "Event %d not found", eventId)
+		}
+		return nil
+	})
+	return event, err
+}
+
+func (s *BoltStorage) ActiveEvent(chatId int64) (Event, error) {
+	events, err := s.FindEvents(chatId)
+	if err != nil {
+		return Event{}, err
+	}
+	for _, e := range events {
+		if e.Active {
+			return e, nil
+		}
+	}
+	return s.FindEvent(chatId, 0)
+}
+
+func (s *BoltStorage) SaveEvent(event Event) error {
+	err := s.db.Update(func(tx *bolt.Tx) (err error) {
+		var eventsBkt *bolt.Bucket
+
+		if eventsBkt, err = s.makeEventsBucket(tx, event.ChatId); err != nil {
+			return err
+		}
+
+		// the event saved with its own id like the imported one must not be
+		// overwritten by the next created event
+		if uint64(event.Id) > eventsBkt.Sequence() {
+			if err = eventsBkt.SetSequence(uint64(event.Id)); err != nil {
+				return errors.Wrap(err, "failed to set event sequence")
+			}
+		}
+		return s.save(eventsBkt, strconv.Itoa(event.Id), event)
+	})
+	return errors.Wrapf(err, "Failed to save event")
+}
+
+func (s *BoltStorage) SwitchEvent(chatId int64, eventId int) (event Event, err error) {
+	event = Event{ChatId: chatId}
+	err = s.db.Update(func(tx *bolt.Tx) (err error) {
+		var eventsBkt *bolt.Bucket
+
+		if eventsBkt, err = s.makeEventsBucket(tx, chatId); err != nil {
+			return err
+		}
+
+		value := eventsBkt.Get([]byte(strconv.Itoa(eventId)))
+		if value == nil && eventId != 0 {
+			return NewError(ErrNotFound, "Event %d not found", eventId)
+		}
+		if value != nil {
+			if err = json.Unmarshal(value, &event); err != nil {
+				return errors.Wrap(err, "failed to unmarshal")
+			}
+		}
+
+		if err = s.deactivateEvents(eventsBkt); err != nil {
+			return err
+		}
+
+		if event.IsDefault() {
+			return nil
+		}
+		event.Active = true
+		return s.save(eventsBkt, strconv.Itoa(event.Id), event)
+	})
+	return event, err
+}
+
+func (s *BoltStorage) deactivateEvents(eventsBkt *bolt.Bucket) error {
+	var active []Event
+	err := eventsBkt.ForEach(func(k, v []byte) error {
+		event := Event{}
+		if e := json.Unmarshal(v, &event); e != nil {
+			return errors.Wrap(e, "failed to unmarshal")
+		}
+		if event.Active {
+			active = append(active, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, event := range active {
+		event.Active = false
+		if err = s.save(eventsBkt, strconv.Itoa(event.Id), event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStorage) makeEventsBucket(tx *bolt.Tx, chatId int64) (*bolt.Bucket, error) {
+	chatBkt, err := s.makeChatBucket(tx, chatId)
+	if err != nil {
+		return nil, err
+	}
+	res, err := chatBkt.CreateBucketIfNotExists([]byte(eventsBucketName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "no bucket %s in chat %d", eventsBucketName, chatId)
+	}
+	return res, nil
+}
+
+func (s *BoltStorage) SaveMember(member Member) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		chatBkt, err := s.makeMembersBucket(tx, member.ChatId)
+		if err != nil {
+			return err
+		}
+		return s.save(chatBkt, member.Id(), member)
+	})
+	return errors.Wrapf(err, "Failed to save member")
+}
+
+func (s *BoltStorage) DeleteMember(member Member) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		chatBkt, err := s.makeMembersBucket(tx, member.ChatId)
+		if err != nil {
+			return err
+		}
+		return chatBkt.Delete([]byte(member.Id()))
+	})
+	return errors.Wrapf(err, "Failed to delete member")
+}
+
+func (s *BoltStorage) FindMembers(chatId int64) (members []Member, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		chatBkt := tx.Bucket([]byte(membersBucketName)).Bucket([]byte(strconv.FormatInt(chatId, 10)))
+		if chatBkt == nil {
+			return nil
+		}
+		return chatBkt.ForEach(func(k, v []byte) error {
+			member := Member{}
+			if e := json.Unmarshal(v, &member); e != nil {
+				return errors.Wrap(e, "failed to unmarshal")
+			}
+			members = append(members, member)
+			return nil
+		})
+	})
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Time.Before(members[j].Time)
+	})
+	return members, err
+}
+
+func (s *BoltStorage) makeMembersBucket(tx *bolt.Tx, chatId int64) (*bolt.Bucket, error) {
+	membersBkt := tx.Bucket([]byte(membersBucketName))
+	if membersBkt == nil {
+		return nil, errors.Errorf("no bucket %s", membersBucketName)
+	}
+	res, err := membersBkt.CreateBucketIfNotExists([]byte(strconv.FormatInt(chatId, 10)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "no bucket %d in %s", chatId, membersBucketName)
+	}
+	return res, nil
+}
+
+// FindSettings returns the settings of the chat, the empty ones if not saved
+func (s *BoltStorage) FindSettings(chatId int64) (settings Settings, err error) {
+	settings.ChatId = chatId
+	err = s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket([]byte(settingsBucketName)).Get([]byte(strconv.FormatInt(chatId, 10)))
+		if value == nil {
+			return nil
+		}
+		return errors.Wrap(json.Unmarshal(value, &settings), "failed to unmarshal")
+	})
+	return settings, errors.Wrap(err, "Failed to find settings")
+}
+
+func (s *BoltStorage) SaveSettings(settings Settings) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return s.save(tx.Bucket([]byte(settingsBucketName)), strconv.FormatInt(settings.ChatId, 10), settings)
+	})
+	return errors.Wrap(err, "Failed to save settings")
+}
+
+func (s *BoltStorage) ArchiveEvent(event Event, start time.Time) (archive Archive, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) (err error) {
+		var chatBkt, eventsBkt, archiveBkt *bolt.Bucket
+
+		if chatBkt, err = s.makeChatBucket(tx, event.ChatId); err != nil {
+			return err
+		}
+		if eventsBkt, err = s.makeEventsBucket(tx, event.ChatId); err != nil {
+			return err
+		}
+		if archiveBkt, err = s.makeArchiveBucket(tx, event.ChatId); err != nil {
+			return err
+		}
+
+		participants, err := s.participants(chatBkt, event.Id)
+		if err != nil {
+			return err
+		}
+
+		if len(participants) > 0 {
+			id, err := archiveBkt.NextSequence()
+			if err != nil {
+				return errors.Wrap(err, "failed to get next archive id")
+			}
+			archive = Archive{
+				Id:           int(id),
+				Event:        event,
+				Participants: participants,
+				Time:         time.Now(),
+			}
+			if err = s.save(archiveBkt, strconv.Itoa(archive.Id), archive); err != nil {
+				return err
+			}
+		}
+
+		for _, p := range participants {
+			if err = chatBkt.Delete([]byte(p.Id())); err != nil {
+				return err
+			}
+		}
+
+		if start.IsZero() {
+			return nil
+		}
+		reopened := Event{Id: event.Id, ChatId: event.ChatId}
+		if value := eventsBkt.Get([]byte(strconv.Itoa(event.Id))); value != nil {
+			if err = json.Unmarshal(value, &reopened); err != nil {
+				return errors.Wrap(err, "failed to unmarshal")
+			}
+		} else if event.Id != 0 {
+			return NewError(ErrNotFound, "Event %d not found", event.Id)
+		}
+		reopened.Start = start
+		reopened.MessageId = 0
+		return s.save(eventsBkt, strconv.Itoa(reopened.Id), reopened)
+	})
+	return archive, errors.Wrapf(err, "Failed to archive event")
+}
+
+func (s *BoltStorage) RestoreArchive(chatId int64, archiveId int) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		chatBkt, err := s.makeChatBucket(tx, chatId)
+		if err != nil {
+			return err
+		}
+		archiveBkt, err := s.makeArchiveBucket(tx, chatId)
+		if err != nil {
+			return err
+		}
+
+		key := []byte(strconv.Itoa(archiveId))
+		value := archiveBkt.Get(key)
+		if value == nil {
+			return NewError(ErrNotFound, "Archive %d not found", archiveId)
+		}
+		archive := Archive{}
+		if err = json.Unmarshal(value, &archive); err != nil {
+			return errors.Wrap(err, "failed to unmarshal")
+		}
+		for _, p := range archive.Participants {
+			if err = s.save(chatBkt, p.Id(), p); err != nil {
+				return err
+			}
+		}
+		return archiveBkt.Delete(key)
+	})
+	return errors.Wrap(err, "Failed to restore archive")
+}
+
+func (s *BoltStorage) PushOperation(op Operation) (saved Operation, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		journal, err := s.journal(tx, op.ChatId)
+		if err != nil {
+			return err
+		}
+		journal, saved = pushOperation(journal, op)
+		return s.save(tx.Bucket([]byte(journalBucketName)), strconv.FormatInt(op.ChatId, 10), journal)
+	})
+	return saved, errors.Wrap(err, "Failed to save operation")
+}
+
+func (s *BoltStorage) LastOperation(chatId int64) (op Operation, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		journal, err := s.journal(tx, chatId)
+		if err != nil {
+			return err
+		}
+		op, err = lastOperation(journal)
+		return err
+	})
+	return op, errors.Wrap(err, "Failed to find operation")
+}
+
+func (s *BoltStorage) DeleteOperation(op Operation) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		journal, err := s.journal(tx, op.ChatId)
+		if err != nil {
+			return err
+		}
+		if journal, err = removeOperation(journal, op.Id); err != nil {
+			return err
+		}
+		return s.save(tx.Bucket([]byte(journalBucketName)), strconv.FormatInt(op.ChatId, 10), journal)
+	})
+	return errors.Wrap(err, "Failed to delete operation")
+}
+
+func (s *BoltStorage) journal(tx *bolt.Tx, chatId int64) (journal []Operation, err error) {
+	value := tx.Bucket([]byte(journalBucketName)).Get([]byte(strconv.FormatInt(chatId, 10)))
+	if value == nil {
+		return nil, nil
+	}
+	return journal, errors.Wrap(json.Unmarshal(value, &journal), "failed to unmarshal")
+}
+
+func (s *BoltStorage) FindArchive(chatId int64) (archive []Archive, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		chatBkt := tx.Bucket([]byte(archiveBucketName)).Bucket([]byte(strconv.FormatInt(chatId, 10)))
+		if chatBkt == nil {
+			return nil
+		}
+		return s.archive(chatBkt, &archive)
+	})
+	sort.Slice(archive, func(i, j int) bool {
+		return archive[i].Id < archive[j].Id
+	})
+	return archive, err
+}
+
+func (s *BoltStorage) FindAllArchive() (archive []Archive, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		archiveBkt := tx.Bucket([]byte(archiveBucketName))
+		return archiveBkt.ForEach(func(k, v []byte) error {
+			return s.archive(archiveBkt.Bucket(k), &archive)
+		})
+	})
+	sort.Slice(archive, func(i, j int) bool {
+		return archive[i].Time.Before(archive[j].Time)
+	})
+	return archive, err
+}
+
+func (s *BoltStorage) archive(chatBkt *bolt.Bucket, archive *[]Archive) error {
+	return chatBkt.ForEach(func(k, v []byte) error {
+		a := Archive{}
+		if e := json.Unmarshal(v, &a); e != nil {
+			return errors.Wrap(e, "failed to unmarshal")
+		}
+		*archive = append(*archive, a)
+		return nil
+	})
+}
+
+func (s *BoltStorage) makeArchiveBucket(tx *bolt.Tx, chatId int64) (*bolt.Bucket, error) {
+	archiveBkt := tx.Bucket([]byte(archiveBucketName))
+	if archiveBkt == nil {
+		return nil, errors.Errorf("no bucket %s", archiveBucketName)
+	}
+	res, err := archiveBkt.CreateBucketIfNotExists([]byte(strconv.FormatInt(chatId, 10)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "no bucket %d in %s", chatId, archiveBucketName)
+	}
+	return res, nil
+}
+
+func (s *BoltStorage) ClaimJob(key string, now time.Time) (claimed bool, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		jobsBkt := tx.Bucket([]byte(jobsBucketName))
+		if jobsBkt.Get([]byte(key)) != nil {
+			return nil
+		}
+		claimed = true
+		return s.save(jobsBkt, key, now)
+	})
+	return claimed, errors.Wrapf(err, "Failed to claim job %s", key)
+}
+
+func (s *BoltStorage) DeleteJobs(before time.Time) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		jobsBkt := tx.Bucket([]byte(jobsBucketName))
+		var keys [][]byte
+		err := jobsBkt.ForEach(func(k, v []byte) error {
+			var claimed time.Time
+			if e := json.Unmarshal(v, &claimed); e != nil {
+				return errors.Wrap(e, "failed to unmarshal")
+			}
+			if claimed.Before(before) {
+				keys = append(keys, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err = jobsBkt.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrapf(err, "Failed to delete jobs")
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNotFound is the kind of error for a missing participant or event
+	ErrNotFound = errors.New("not found")
+	// ErrCapacity is the kind of error for a list with the full waitlist
+	ErrCapacity = errors.New("the list is full")
+	// ErrInvalid is the kind of error for a wrong value of a setting
+	ErrInvalid = errors.New("invalid value")
+)
+
+// Error is the failure caused by the request rather than by the storage.
+// Its message can be shown in the chat, errors.Is matches it with its kind.
+// The format and the args are kept to translate the message, the format
+// may have markdown and the args are escaped when it is shown.
+type Error struct {
+	Kind    error
+	Message string
+	Format  string
+	Args    []interface{}
+}
+
+func NewError(kind error, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), Format: format, Args: args}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"time"
+)
+
+const defaultEventTitle = "Default"
+
+type Event struct {
+	Id          int
+	ChatId      int64
+	Title       string
+	Start       time.Time
+	Location    string
+	Description string
+	Capacity    int
+	Recurrence  *Recurrence
+	Reminders   []time.Duration
+	RemindPing  bool
+	MessageId   int
+	Pinned      bool
+	Active      bool
+	Time        time.Time
+}
+
+func (e *Event) Name() string {
+	if e.Title == "" {
+		return defaultEventTitle
+	}
+	return e.Title
+}
+
+func (e *Event) IsDefault() bool {
+	return e.Id == 0
+}
+
+func (e *Event) HasDetails() bool {
+	return e.Title != "" || !e.Start.IsZero() || e.Location != "" || e.Description != "" ||
+		e.Recurrence != nil || len(e.Reminders) > 0
+}
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// ChatExport keeps the lists of the chat to move them between the bots
+type ChatExport struct {
+	ChatId       int64
+	Events       []Event
+	Participants []Participant
+	Time         time.Time
+}
+
+var csvHeader = []string{"chat_id", "event_id", "event", "number", "status",
+	"username", "first_name", "last_name", "user_id", "type", "time", "owner_uid", "owner_username"}
+
+// csvOwnerColumns are the last columns missing in the files written before the guests had owners
+const csvOwnerColumns = 2
+
+// ExportChat collects the events of the chat with their participants
+func ExportChat(r Repository, chatId int64) (export ChatExport, err error) {
+	export = ChatExport{ChatId: chatId, Time: time.Now()}
+
+	events, err := r.FindEvents(chatId)
+	if err != nil {
+		return export, err
+	}
+	if len(events) == 0 || !events[0].IsDefault() {
+		events = append([]Event{{ChatId: chatId}}, events...)
+	}
+
+	for _, e := range events {
+		participants, err := r.FindByEvent(e)
+		if err != nil {
+			return export, err
+		}
+		export.Participants = append(export.Participants, participants...)
+	}
+	export.Events = events
+	return export, nil
+}
+
+// ImportChat saves the events and the participants to the chat. The event
+// already saved in the chat under the same id and name is the same one, it keeps
+// its details and the participants with the same ids are replaced. The imported
+// event colliding with another one gets a new id. The list messages of the other
+// bot are forgotten. It returns the number of the imported participants.
+func ImportChat(r Repository, export ChatExport, chatId int64) (int, error) {
+	if chatId == 0 {
+		chatId = export.ChatId
+	}
+
+	events, err := r.FindEvents(chatId)
+	if err != nil {
+		return 0, err
+	}
+	saved := map[int]Event{}
+	next := 1
+	for _, e := range append(events, export.Events...) {
+		if e.Id >= next {
+			next = e.Id + 1
+		}
+	}
+	for _, e := range events {
+		saved[e.Id] = e
+	}
+
+	// ids maps the imported ids to the ones in the chat
+	ids := map[int]int{}
+	active := -1
+	for _, e := range export.Events {
+		ids[e.Id] = e.Id
+		if s, ok := saved[e.Id]; ok {
+			if s.Name() == e.Name() {
+				continue
+			}
+			ids[e.Id] = next
+			e.Id = next
+			next++
+		}
+		e.ChatId = chatId
+		e.MessageId = 0
+		if e.Active {
+			active = e.Id
+		}
+		if err := r.SaveEvent(e); err != nil {
+			return 0, err
+		}
+	}
+	if active >= 0 {
+		if _, err := r.SwitchEvent(chatId, active); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, p := range export.Participants {
+		p.ChatId = chatId
+		if id, ok := ids[p.EventId]; ok {
+			p.EventId = id
+		}
+		if _, err := r.Create(p); err != nil {
+			return i, err
+		}
+	}
+	return len(export.Participants), nil
+}
+
+func WriteJSON(w io.Writer, export ChatExport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return errors.Wrap(encoder.Encode(export), "failed to write json")
+}
+
+func ReadJSON(r io.Reader) (export ChatExport, err error) {
+	err = json.NewDecoder(r).Decode(&export)
+	return export, errors.Wrap(err, "failed to read json")
+}
+
+// WriteCSV writes a row for every participant in the order of the lists
+func WriteCSV(w io.Writer, export ChatExport) error {
+	titles := map[int]string{}
+	for _, e := range export.Events {
+		titles[e.Id] = e.Name()
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return errors.Wrap(err, "failed to write csv")
+	}
+	number := map[int]int{}
+	for _, p := range export.Participants {
+		number[p.EventId]++
+		err := writer.Write([]string{
+			strconv.FormatInt(export.ChatId, 10),
+			strconv.Itoa(p.EventId),
+			titles[p.EventId],
+			strconv.Itoa(number[p.EventId]),
+			string(p.State()),
+			p.User.UserName,
+			p.User.FirstName,
+			p.User.LastName,
+			p.User.Id,
+			string(p.User.Type),
+			p.Time.Format(time.RFC3339Nano),
+			p.ownerUid(),
+			ownerUserName(p),
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to write csv")
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "failed to write csv")
+}
+
+// ReadCSV reads the rows written by WriteCSV. The events are made from the
+// ids and the titles, the other details are not kept in the csv.
+func ReadCSV(r io.Reader) (export ChatExport, err error) {
+	reader := csv.NewReader(r)
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return export, errors.Wrap(err, "failed to read csv")
+	}
+	if len(rows) == 0 {
+		return export, errors.New("empty csv")
+	}
+	if n := len(rows[0]); n != len(csvHeader) && n != len(csvHeader)-csvOwnerColumns {
+		return export, errors.Errorf("wrong number of fields: %d", n)
+	}
+
+	events := map[int]bool{}
+	for i, row := range rows[1:] {
+		line := i + 2
+		chatId, err := strconv.ParseInt(row[0], 10, 64)
+		if err != nil {
+			return export, errors.Errorf("wrong chat id in line %d", line)
+		}
+		if export.ChatId != 0 && export.ChatId != chatId {
+			return export, errors.Errorf("another chat in line %d", line)
+		}
+		export.ChatId = chatId
+
+		eventId, err := strconv.Atoi(row[1])
+		if err != nil {
+			return export, errors.Errorf("wrong event id in line %d", line)
+		}
+		if !events[eventId] {
+			events[eventId] = true
+			event := Event{Id: eventId, ChatId: chatId, Title: row[2]}
+			if event.IsDefault() && event.Title == defaultEventTitle {
+				event.Title = ""
+			}
+			export.Events = append(export.Events, event)
+		}
+
+		joined, err := time.Parse(time.RFC3339, row[10])
+		if err != nil {
+			return export, errors.Errorf("wrong time in line %d", line)
+		}
+		owner, err := parseOwner(row)
+		if err != nil {
+			return export, errors.Errorf("wrong owner in line %d", line)
+		}
+		export.Participants = append(export.Participants, Participant{
+			Owner: owner,
+			User: User{
+				UserName:  row[5],
+				FirstName: row[6],
+				LastName:  row[7],
+				Id:        row[8],
+				Type:      UserType(row[9]),
+			},
+			Time:    joined,
+			ChatId:  chatId,
+			EventId: eventId,
+			Status:  Status(row[4]),
+		})
+	}
+	return export, nil
+}
+
+func ownerUserName(p Participant) string {
+	if p.Owner == nil {
+		return ""
+	}
+	return p.Owner.UserName
+}
+
+// parseOwner makes the owner of the guest from the uid like "telegram:42" and
+// the username, nil if the row has no owner
+func parseOwner(row []string) (*User, error) {
+	if len(row) < len(csvHeader) || row[11] == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(row[11], ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, errors.Errorf("wrong owner uid %s", row[11])
+	}
+	return &User{Id: parts[1], UserName: row[12], Type: UserType(parts[0])}, nil
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func exportedChat(t *testing.T) ChatExport {
+	s := NewMemoryStorage()
+	now := time.Now()
+	s.Create(Participant{User: User{Id: "1", UserName: "smith", FirstName: "John", Type: UserTelegram}, ChatId: 1, Time: now})
+	event, _ := s.CreateEvent(1, "Board games, Friday")
+	event.Location = "Cafe"
+	_ = s.SaveEvent(event)
+	s.Create(Participant{User: User{UserName: "My brother", Type: UserGuest}, ChatId: 1, EventId: event.Id,
+		Time: now, Status: StatusMaybe})
+
+	export, err := ExportChat(s, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Events) != 2 || len(export.Participants) != 2 {
+		t.Fatalf("export %+v", export)
+	}
+	return export
+}
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			var data ChatExport
+			var err error
+			if format == FormatCSV {
+				err = WriteCSV(&buf, exportedChat(t))
+				if err == nil {
+					data, err = ReadCSV(&buf)
+				}
+			} else {
+				err = WriteJSON(&buf, exportedChat(t))
+				if err == nil {
+					data, err = ReadJSON(&buf)
+				}
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s := NewMemoryStorage()
+			if n, err := ImportChat(s, data, 2); err != nil || n != 2 {
+				t.Fatalf("imported %d: %v", n, err)
+			}
+			var event Event
+			events, _ := s.FindEvents(2)
+			for _, e := range events {
+				if e.Title == "Board games, Friday" {
+					event = e
+				}
+			}
+			if event.Id == 0 {
+				t.Fatalf("event is not imported: %+v", events)
+			}
+			if active, _ := s.ActiveEvent(2); format == FormatJSON && active.Id != event.Id {
+				t.Errorf("active event %+v", active)
+			}
+			if format == FormatJSON && event.Location != "Cafe" {
+				t.Errorf("event details are lost: %+v", event)
+			}
+			participants, _ := s.FindByEvent(event)
+			if len(participants) != 1 || participants[0].Name() != "My brother" || participants[0].State() != StatusMaybe {
+				t.Errorf("participants %+v", participants)
+			}
+			if n, _ := s.CountByEvent(Event{ChatId: 2}); n != 1 {
+				t.Errorf("%d participants in the default event", n)
+			}
+		})
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	for name, text := range map[string]string{
+		"empty":        "",
+		"wrong fields": "chat_id,event_id\n1,0\n",
+		"wrong chat":   "h,h,h,h,h,h,h,h,h,h,h\nx,0,,1,going,a,,,,guest,2020-01-01T00:00:00Z\n",
+		"two chats": "h,h,h,h,h,h,h,h,h,h,h\n1,0,,1,going,a,,,,guest,2020-01-01T00:00:00Z\n" +
+			"2,0,,1,going,b,,,,guest,2020-01-01T00:00:00Z\n",
+	} {
+		if _, err := ReadCSV(bytes.NewBufferString(text)); err == nil {
+			t.Errorf("%s csv is read", name)
+		}
+	}
+}
+
+func TestCSVGuestOwner(t *testing.T) {
+	smith := User{Id: "42", UserName: "smith", FirstName: "John", Type: UserTelegram}
+	export := ChatExport{ChatId: 1, Events: []Event{{ChatId: 1}}, Participants: []Participant{
+		{User: smith, ChatId: 1, Time: time.Now()},
+		{User: NewGuest(smith, "John"), Owner: &smith, ChatId: 1, Time: time.Now()},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, export); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Participants) != 2 || data.Participants[0].Owner != nil {
+		t.Fatalf("participants %+v", data.Participants)
+	}
+	guest := data.Participants[1]
+	if !guest.IsGuestOf(smith) || guest.Owner.Link() != "@smith" || guest.User.Uid() != NewGuest(smith, "John").Uid() {
+		t.Errorf("guest %+v, owner %+v", guest, guest.Owner)
+	}
+
+	old := "h,h,h,h,h,h,h,h,h,h,h\n1,0,,1,going,a,,,,guest,2020-01-01T00:00:00Z\n"
+	if data, err := ReadCSV(bytes.NewBufferString(old)); err != nil || data.Participants[0].Owner != nil {
+		t.Errorf("csv without the owner columns: %+v, %v", data, err)
+	}
+}
+
+func TestImportIds(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			chess, err := s.CreateEvent(2, "Chess")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, err := ImportChat(s, exportedChat(t), 2); err != nil || n != 2 {
+				t.Fatalf("imported %d: %v", n, err)
+			}
+			if n, _ := s.CountByEvent(chess); n != 0 {
+				t.Errorf("%d participants merged into the event with the same id", n)
+			}
+
+			created, err := s.CreateEvent(2, "Go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			events, _ := s.FindEvents(2)
+			if len(events) != 4 {
+				t.Errorf("events %+v", events)
+			}
+			if n, _ := s.CountByEvent(created); n != 0 {
+				t.Errorf("created event %+v has %d participants", created, n)
+			}
+		})
+	}
+}
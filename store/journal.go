@@ -0,0 +1,62 @@
+package store
+
+import (
+	"time"
+)
+
+// JournalLength is the number of the recent operations kept for undo in every chat
+const JournalLength = 10
+
+type OperationKind string
+
+const (
+	OperationReset  OperationKind = "reset"
+	OperationRemove OperationKind = "remove"
+)
+
+// Operation is a destructive change of the list which can be undone
+type Operation struct {
+	Id      int
+	ChatId  int64
+	EventId int
+	Kind    OperationKind
+	// User made the change
+	User User
+	// Participants are the removed ones
+	Participants []Participant
+	// ArchiveId is the archive made by the reset
+	ArchiveId int
+	Time      time.Time
+}
+
+// pushOperation appends the operation with the next id
+// and keeps the last JournalLength operations
+func pushOperation(journal []Operation, op Operation) ([]Operation, Operation) {
+	op.Id = 1
+	if len(journal) > 0 {
+		op.Id = journal[len(journal)-1].Id + 1
+	}
+	journal = append(journal, op)
+	if len(journal) > JournalLength {
+		journal = journal[len(journal)-JournalLength:]
+	}
+	return journal, op
+}
+
+func lastOperation(journal []Operation) (Operation, error) {
+	if len(journal) == 0 {
+		return Operation{}, NewError(ErrNotFound, "Nothing to undo")
+	}
+	return journal[len(journal)-1], nil
+}
+
+// removeOperation fails if the operation is already removed,
+// so the same change is never undone twice
+func removeOperation(journal []Operation, id int) ([]Operation, error) {
+	for i, op := range journal {
+		if op.Id == id {
+			return append(journal[:i:i], journal[i+1:]...), nil
+		}
+	}
+	return journal, NewError(ErrNotFound, "Nothing to undo")
+}
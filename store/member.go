@@ -0,0 +1,18 @@
+package store
+
+import (
+	"time"
+)
+
+// Member is a chat member seen by the bot
+type Member struct {
+	User   User
+	Time   time.Time
+	ChatId int64
+	// LanguageCode is the language of the Telegram client of the user
+	LanguageCode string
+}
+
+func (m *Member) Id() string {
+	return m.User.Uid()
+}
@@ -0,0 +1,413 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStorage keeps everything in maps and loses it on exit.
+// It is used in tests and for trying the bot out.
+type MemoryStorage struct {
+	mu           sync.Mutex
+	participants map[int64]map[string]Participant
+	events       map[int64]map[int]Event
+	members      map[int64]map[string]Member
+	archive      map[int64][]Archive
+	archiveSeq   map[int64]int
+	settings     map[int64]Settings
+	journal      map[int64][]Operation
+	jobs         map[string]time.Time
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		participants: map[int64]map[string]Participant{},
+		events:       map[int64]map[int]Event{},
+		members:      map[int64]map[string]Member{},
+		archive:      map[int64][]Archive{},
+		archiveSeq:   map[int64]int{},
+		settings:     map[int64]Settings{},
+		journal:      map[int64][]Operation{},
+		jobs:         map[string]time.Time{},
+	}
+}
+
+func (s *MemoryStorage) Close() error {
+	return nil
+}
+
+func (s *MemoryStorage) Create(participant Participant) (Participant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.participants[participant.ChatId] == nil {
+		s.participants[participant.ChatId] = map[string]Participant{}
+	}
+	s.participants[participant.ChatId][participant.Id()] = participant
+	return participant, nil
+}
+
+func (s *MemoryStorage) Delete(participant Participant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.participants[participant.ChatId], participant.Id())
+	return nil
+}
+
+func (s *MemoryStorage) DeleteByEvent(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.eventParticipants(event) {
+		delete(s.participants[event.ChatId], p.Id())
+	}
+	return nil
+}
+
+func (s *MemoryStorage) Find(p Participant) (Participant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	participant, ok := s.participants[p.ChatId][p.Id()]
+	if !ok {
+		return participant, NewError(ErrNotFound, "Participant %s not found", p.Id())
+	}
+	return participant, nil
+}
+
+func (s *MemoryStorage) FindByNumber(number int, event Event) (Participant, error) {
+	participants, _ := s.FindByEvent(event)
+	return findByNumber(participants, number)
+}
+
+func (s *MemoryStorage) FindByName(name string, event Event) (Participant, error) {
+	participants, _ := s.FindByEvent(event)
+	return findByName(participants, name)
+}
+
+func (s *MemoryStorage) FindByLink(name string, event Event) (Participant, error) {
+	participants, _ := s.FindByEvent(event)
+	return findByLink(participants, name)
+}
+
+func (s *MemoryStorage) FindAll() (participants []Participant, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	participants = []Participant{}
+	for _, chat := range s.participants {
+		for _, p := range chat {
+			participants = append(participants, p)
+		}
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].Time.After(participants[j].Time)
+	})
+	return participants, nil
+}
+
+func (s *MemoryStorage) FindByEvent(event Event) ([]Participant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	participants := s.eventParticipants(event)
+	sortByStatus(participants)
+	return participants, nil
+}
+
+func (s *MemoryStorage) CountByEvent(event Event) (int, error) {
+	participants, _ := s.FindByEvent(event)
+	return len(participants), nil
+}
+
+// AddIfAbsent adds the participant unless the same person is already in the list
+func (s *MemoryStorage) AddIfAbsent(participant Participant, capacity int) (Participant, AddResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	participants := s.eventParticipants(Event{Id: participant.EventId, ChatId: participant.ChatId})
+	stored, replaced, result, err := addIfAbsent(participants, participant, capacity)
+	if err != nil || result == Duplicate {
+		return stored, result, err
+	}
+	if replaced != nil {
+		delete(s.participants[participant.ChatId], replaced.Id())
+	}
+	if s.participants[participant.ChatId] == nil {
+		s.participants[participant.ChatId] = map[string]Participant{}
+	}
+	s.participants[participant.ChatId][stored.Id()] = stored
+	return stored, result, nil
+}
+
+func (s *MemoryStorage) eventParticipants(event Event) (participants []Participant) {
+	for _, p := range s.participants[event.ChatId] {
+		if p.EventId == event.Id {
+			participants = append(participants, p)
+		}
+	}
+	return participants
+}
+
+func (s *MemoryStorage) CreateEvent(chatId int64, title string) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deactivateEvents(chatId)
+
+	id := 1
+	for _, e := range s.events[chatId] {
+		if e.Id >= id {
+			id = e.Id + 1
+		}
+	}
+	event := Event{
+		Id:     id,
+		ChatId: chatId,
+		Title:  title,
+		Active: true,
+		Time:   time.Now(),
+	}
+	s.saveEvent(event)
+	return event, nil
+}
+
+func (s *MemoryStorage) FindEvents(chatId int64) (events []Event, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.events[chatId] {
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id < events[j].Id
+	})
+	return events, nil
+}
+
+func (s *MemoryStorage) FindAllEvents() (events []Event, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, chat := range s.events {
+		for _, e := range chat {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (s *MemoryStorage) FindEvent(chatId int64, eventId int) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if event, ok := s.events[chatId][eventId]; ok {
+		return event, nil
+	}
+	if eventId != 0 {
+		return Event{ChatId: chatId}, NewError(ErrNotFound, "Event %d not found", eventId)
+	}
+	return Event{ChatId: chatId}, nil
+}
+
+func (s *MemoryStorage) ActiveEvent(chatId int64) (Event, error) {
+	events, _ := s.FindEvents(chatId)
+	for _, e := range events {
+		if e.Active {
+			return e, nil
+		}
+	}
+	return s.FindEvent(chatId, 0)
+}
+
+func (s *MemoryStorage) SaveEvent(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saveEvent(event)
+	return nil
+}
+
+func (s *MemoryStorage) SwitchEvent(chatId int64, eventId int) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event, ok := s.events[chatId][eventId]
+	if !ok {
+		if eventId != 0 {
+			return Event{ChatId: chatId}, NewError(ErrNotFound, "Event %d not found", eventId)
+		}
+		event = Event{ChatId: chatId}
+	}
+
+	s.deactivateEvents(chatId)
+	if event.IsDefault() {
+		return event, nil
+	}
+	event.Active = true
+	s.saveEvent(event)
+	return event, nil
+}
+
+func (s *MemoryStorage) saveEvent(event Event) {
+	if s.events[event.ChatId] == nil {
+		s.events[event.ChatId] = map[int]Event{}
+	}
+	s.events[event.ChatId][event.Id] = event
+}
+
+func (s *MemoryStorage) deactivateEvents(chatId int64) {
+	for id, e := range s.events[chatId] {
+		if e.Active {
+			e.Active = false
+			s.events[chatId][id] = e
+		}
+	}
+}
+
+func (s *MemoryStorage) SaveMember(member Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.members[member.ChatId] == nil {
+		s.members[member.ChatId] = map[string]Member{}
+	}
+	s.members[member.ChatId][member.Id()] = member
+	return nil
+}
+
+func (s *MemoryStorage) DeleteMember(member Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.members[member.ChatId], member.Id())
+	return nil
+}
+
+func (s *MemoryStorage) FindMembers(chatId int64) (members []Member, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.members[chatId] {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Time.Before(members[j].Time)
+	})
+	return members, nil
+}
+
+// FindSettings returns the settings of the chat, the empty ones if not saved
+func (s *MemoryStorage) FindSettings(chatId int64) (Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings, ok := s.settings[chatId]
+	if !ok {
+		return Settings{ChatId: chatId}, nil
+	}
+	return settings, nil
+}
+
+func (s *MemoryStorage) SaveSettings(settings Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings[settings.ChatId] = settings
+	return nil
+}
+
+func (s *MemoryStorage) ArchiveEvent(event Event, start time.Time) (archive Archive, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	participants := s.eventParticipants(event)
+	if len(participants) > 0 {
+		s.archiveSeq[event.ChatId]++
+		archive = Archive{
+			Id:           s.archiveSeq[event.ChatId],
+			Event:        event,
+			Participants: participants,
+			Time:         time.Now(),
+		}
+		s.archive[event.ChatId] = append(s.archive[event.ChatId], archive)
+	}
+	for _, p := range participants {
+		delete(s.participants[event.ChatId], p.Id())
+	}
+	if start.IsZero() {
+		return archive, nil
+	}
+	reopened, ok := s.events[event.ChatId][event.Id]
+	if !ok && event.Id != 0 {
+		return archive, NewError(ErrNotFound, "Event %d not found", event.Id)
+	}
+	if !ok {
+		reopened = Event{ChatId: event.ChatId}
+	}
+	reopened.Start = start
+	reopened.MessageId = 0
+	s.saveEvent(reopened)
+	return archive, nil
+}
+
+func (s *MemoryStorage) RestoreArchive(chatId int64, archiveId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, a := range s.archive[chatId] {
+		if a.Id != archiveId {
+			continue
+		}
+		if s.participants[chatId] == nil {
+			s.participants[chatId] = map[string]Participant{}
+		}
+		for _, p := range a.Participants {
+			s.participants[chatId][p.Id()] = p
+		}
+		s.archive[chatId] = append(s.archive[chatId][:i:i], s.archive[chatId][i+1:]...)
+		return nil
+	}
+	return NewError(ErrNotFound, "Archive %d not found", archiveId)
+}
+
+func (s *MemoryStorage) PushOperation(op Operation) (saved Operation, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal[op.ChatId], saved = pushOperation(s.journal[op.ChatId], op)
+	return saved, nil
+}
+
+func (s *MemoryStorage) LastOperation(chatId int64) (Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return lastOperation(s.journal[chatId])
+}
+
+func (s *MemoryStorage) DeleteOperation(op Operation) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal[op.ChatId], err = removeOperation(s.journal[op.ChatId], op.Id)
+	return err
+}
+
+func (s *MemoryStorage) FindArchive(chatId int64) ([]Archive, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Archive(nil), s.archive[chatId]...), nil
+}
+
+func (s *MemoryStorage) FindAllArchive() (archive []Archive, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, chat := range s.archive {
+		archive = append(archive, chat...)
+	}
+	sort.Slice(archive, func(i, j int) bool {
+		return archive[i].Time.Before(archive[j].Time)
+	})
+	return archive, nil
+}
+
+func (s *MemoryStorage) ClaimJob(key string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[key]; ok {
+		return false, nil
+	}
+	s.jobs[key] = now
+	return true, nil
+}
+
+func (s *MemoryStorage) DeleteJobs(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, claimed := range s.jobs {
+		if claimed.Before(before) {
+			delete(s.jobs, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+const (
+	metaBucketName   = "meta"
+	schemaVersionKey = "version"
+)
+
+// SchemaVersion is the version of the data layout this build works with
+const SchemaVersion = 1
+
+// Migrator is implemented by the storages whose data layout is versioned
+type Migrator interface {
+	SchemaVersion() (int, error)
+	Migrate(dryRun bool) ([]Migration, error)
+}
+
+// Migration describes the applied migration and the number of changed records
+type Migration struct {
+	Version int
+	Name    string
+	Changed int
+}
+
+type migration struct {
+	version int
+	name    string
+	fn      func(tx *bolt.Tx) (changed int, err error)
+}
+
+// migrations are applied in order, each one moves the schema to its version
+var migrations = []migration{
+	{1, "set the status of the participants saved before the answers", migrateStatus},
+}
+
+var errDryRun = errors.New("dry run")
+
+// CheckSchema fails if the storage has to be migrated or
+// it was written by a newer version of the bot
+func CheckSchema(r Repository) error {
+	m, ok := r.(Migrator)
+	if !ok {
+		return nil
+	}
+	version, err := m.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version < SchemaVersion {
+		return errors.Errorf("schema version %d is older than %d, run tbot migrate", version, SchemaVersion)
+	}
+	if version > SchemaVersion {
+		return errors.Errorf("schema version %d is newer than %d, upgrade the bot", version, SchemaVersion)
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the data layout,
+// 0 for the databases created before the versioning
+func (s *BoltStorage) SchemaVersion() (version int, err error) {
+	err = s.db.View(func(tx *bolt.Tx) (err error) {
+		version, err = schemaVersion(tx)
+		return err
+	})
+	return version, err
+}
+
+// Migrate applies the migrations newer than the schema version. The dry run
+// reports them and rolls the transaction back.
+func (s *BoltStorage) Migrate(dryRun bool) (applied []Migration, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		version, err := schemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		if version > SchemaVersion {
+			return errors.Errorf("schema version %d is newer than %d", version, SchemaVersion)
+		}
+
+		for _, m := range migrations {
+			if m.version <= version {
+				continue
+			}
+			changed, err := m.fn(tx)
+			if err != nil {
+				return errors.Wrapf(err, "migration %d failed", m.version)
+			}
+			applied = append(applied, Migration{Version: m.version, Name: m.name, Changed: changed})
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return setSchemaVersion(tx, SchemaVersion)
+	})
+	if err == errDryRun {
+		err = nil
+	}
+	return applied, err
+}
+
+func schemaVersion(tx *bolt.Tx) (version int, err error) {
+	metaBkt := tx.Bucket([]byte(metaBucketName))
+	if metaBkt == nil {
+		return 0, nil
+	}
+	value := metaBkt.Get([]byte(schemaVersionKey))
+	if value == nil {
+		return 0, nil
+	}
+	return version, errors.Wrap(json.Unmarshal(value, &version), "failed to unmarshal")
+}
+
+func setSchemaVersion(tx *bolt.Tx, version int) error {
+	metaBkt, err := tx.CreateBucketIfNotExists([]byte(metaBucketName))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create bucket %s", metaBucketName)
+	}
+	value, _ := json.Marshal(version)
+	return metaBkt.Put([]byte(schemaVersionKey), value)
+}
+
+type participantRecord struct {
+	bkt         *bolt.Bucket
+	key         []byte
+	participant Participant
+}
+
+// participantRecords reads the participants of all chats, so they
+// can be changed after the iteration
+func participantRecords(tx *bolt.Tx) (records []participantRecord, err error) {
+	chatsBkt := tx.Bucket([]byte(chatsBucketName))
+	err = chatsBkt.ForEach(func(k, v []byte) error {
+		chatBkt := chatsBkt.Bucket(k)
+		if chatBkt == nil {
+			return nil
+		}
+		return chatBkt.ForEach(func(k, v []byte) error {
+			if v == nil { // skip nested buckets
+				return nil
+			}
+			record := participantRecord{bkt: chatBkt, key: append([]byte(nil), k...)}
+			if e := json.Unmarshal(v, &record.participant); e != nil {
+				return errors.Wrapf(e, "failed to unmarshal %s", k)
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	return records, err
+}
+
+// migrateStatus makes the participants without an answer going
+func migrateStatus(tx *bolt.Tx) (changed int, err error) {
+	records, err := participantRecords(tx)
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range records {
+		if r.participant.Status != "" {
+			continue
+		}
+		r.participant.Status = StatusGoing
+		value, _ := json.Marshal(r.participant)
+		if err = r.bkt.Put(r.key, value); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// legacyDB makes the database saved before the versioning: no schema version
+// and the participants without the status
+func legacyDB(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "bolt.db")
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		chatsBkt, err := tx.CreateBucket([]byte(chatsBucketName))
+		if err != nil {
+			return err
+		}
+		chatBkt, err := chatsBkt.CreateBucket([]byte("100"))
+		if err != nil {
+			return err
+		}
+		for _, p := range []Participant{
+			{User: User{Id: "1", UserName: "smith", Type: UserTelegram}, ChatId: 100},
+			{User: User{UserName: "John", Type: UserGuest}, ChatId: 100},
+		} {
+			value, _ := json.Marshal(p)
+			if err = chatBkt.Put([]byte(p.Id()), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMigrate(t *testing.T) {
+	s, err := NewBoltStorage(legacyDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	if err = CheckSchema(s); err == nil {
+		t.Error("old schema is accepted")
+	}
+
+	applied, err := s.Migrate(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0].Changed != 2 {
+		t.Errorf("dry run %+v", applied)
+	}
+	if version, _ := s.SchemaVersion(); version != 0 {
+		t.Errorf("dry run changed the version to %d", version)
+	}
+
+	if _, err = s.Migrate(false); err != nil {
+		t.Fatal(err)
+	}
+	if err = CheckSchema(s); err != nil {
+		t.Error(err)
+	}
+	participants, _ := s.FindByEvent(Event{ChatId: 100})
+	for _, p := range participants {
+		if p.Status != StatusGoing {
+			t.Errorf("%s has status %q", p.Name(), p.Status)
+		}
+	}
+
+	if applied, _ = s.Migrate(false); len(applied) != 0 {
+		t.Errorf("migrated twice: %+v", applied)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	s, err := NewBoltStorage(filepath.Join(t.TempDir(), "bolt.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	if err = CheckSchema(s); err != nil {
+		t.Errorf("new database: %v", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return setSchemaVersion(tx, SchemaVersion+1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = CheckSchema(s); err == nil {
+		t.Error("newer schema is accepted")
+	}
+	if _, err = s.Migrate(false); err == nil {
+		t.Error("newer schema is migrated")
+	}
+}
@@ -7,13 +7,34 @@ import (
 )
 
 type Participant struct {
-	User   User
-	Time   time.Time
-	ChatId int64
+	User    User
+	Time    time.Time
+	ChatId  int64
+	EventId int
+	Status  Status
+	// Owner brought the guest, nil for the others
+	Owner *User
+}
+
+type Status string
+
+const (
+	StatusGoing    Status = "going"
+	StatusMaybe    Status = "maybe"
+	StatusDeclined Status = "declined"
+)
+
+var statusOrder = map[Status]int{
+	StatusGoing:    0,
+	StatusMaybe:    1,
+	StatusDeclined: 2,
 }
 
 func (p *Participant) Id() string {
 	if chatId := strconv.FormatInt(p.ChatId, 10); chatId != "" {
+		if p.EventId != 0 {
+			return fmt.Sprintf("%s.%d.%s", chatId, p.EventId, p.User.Uid())
+		}
 		return fmt.Sprintf("%s.%s", chatId, p.User.Uid())
 	}
 	return p.User.Uid()
@@ -27,6 +48,32 @@ func (p *Participant) Link() string {
 	return p.User.Link()
 }
 
+// IsGuestOf tells if the user brought the guest
+func (p *Participant) IsGuestOf(user User) bool {
+	return p.Owner != nil && p.Owner.Uid() == user.Uid()
+}
+
+// ownerUid returns the owner of the guest, empty for the others
+func (p *Participant) ownerUid() string {
+	if p.Owner == nil {
+		return ""
+	}
+	return p.Owner.Uid()
+}
+
 func (p *Participant) IsUnresolved() bool {
 	return p.User.Type == UserUnresolved
 }
+
+// State returns the answer of the participant, the ones added before
+// the answers were introduced are going
+func (p *Participant) State() Status {
+	if p.Status == "" {
+		return StatusGoing
+	}
+	return p.Status
+}
+
+func (p *Participant) IsGoing() bool {
+	return p.State() == StatusGoing
+}
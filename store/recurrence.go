@@ -0,0 +1,42 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Recurrence is a weekly schedule of the event. The list is reset
+// and reopened for the next week ResetAfter the start.
+type Recurrence struct {
+	Weekday    time.Weekday
+	Hour       int
+	Minute     int
+	ResetAfter time.Duration
+}
+
+// Next returns the first start of the event after the time
+func (r *Recurrence) Next(after time.Time) time.Time {
+	start := time.Date(after.Year(), after.Month(), after.Day(), r.Hour, r.Minute, 0, 0, after.Location())
+	start = start.AddDate(0, 0, (int(r.Weekday)-int(start.Weekday())+7)%7)
+	if !start.After(after) {
+		start = start.AddDate(0, 0, 7)
+	}
+	return start
+}
+
+func (r *Recurrence) String() string {
+	return fmt.Sprintf("every %s %02d:%02d, reset %s after start", r.Weekday, r.Hour, r.Minute, ShortDuration(r.ResetAfter))
+}
+
+// ShortDuration formats the duration without zero minutes and seconds, e.g. 2h instead of 2h0m0s
+func ShortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
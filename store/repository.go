@@ -0,0 +1,226 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Repository keeps the participants, events, members, archive, settings,
+// journal and scheduled jobs of the chats. It is implemented by the bolt, SQLite
+// and in-memory storages.
+type Repository interface {
+	Close() error
+
+	Create(participant Participant) (Participant, error)
+	Delete(participant Participant) error
+	DeleteByEvent(event Event) error
+	Find(p Participant) (Participant, error)
+	FindByNumber(number int, event Event) (Participant, error)
+	FindByName(name string, event Event) (Participant, error)
+	FindByLink(name string, event Event) (Participant, error)
+	FindAll() ([]Participant, error)
+	FindByEvent(event Event) ([]Participant, error)
+	CountByEvent(event Event) (int, error)
+	AddIfAbsent(participant Participant, capacity int) (Participant, AddResult, error)
+
+	CreateEvent(chatId int64, title string) (Event, error)
+	FindEvents(chatId int64) ([]Event, error)
+	FindAllEvents() ([]Event, error)
+	// FindEvent returns the event by id. The default event
+	// exists in every chat even if it was never saved.
+	FindEvent(chatId int64, eventId int) (Event, error)
+	// ActiveEvent returns the event the chat commands act on.
+	// Chats without any created events use the default one.
+	ActiveEvent(chatId int64) (Event, error)
+	SaveEvent(event Event) error
+	SwitchEvent(chatId int64, eventId int) (Event, error)
+
+	SaveMember(member Member) error
+	DeleteMember(member Member) error
+	FindMembers(chatId int64) ([]Member, error)
+
+	// ArchiveEvent moves the participants of the event to the archive at once.
+	// The event is reopened at the start unless it is zero: the stored event gets
+	// the start and a new list message, its other fields are kept. It returns
+	// the saved archive, the empty one if there were no participants. The ids
+	// of the restored archives are not reused.
+	ArchiveEvent(event Event, start time.Time) (Archive, error)
+	// RestoreArchive moves the participants of the archive back
+	// to the list and deletes the archive at once
+	RestoreArchive(chatId int64, archiveId int) error
+	FindArchive(chatId int64) ([]Archive, error)
+	FindAllArchive() ([]Archive, error)
+
+	FindSettings(chatId int64) (Settings, error)
+	SaveSettings(settings Settings) error
+
+	// PushOperation saves the operation to the journal of the chat with the next id
+	PushOperation(op Operation) (Operation, error)
+	LastOperation(chatId int64) (Operation, error)
+	DeleteOperation(op Operation) error
+
+	// ClaimJob marks the scheduled job as done. It returns false
+	// if the job has been already claimed, so it never runs twice
+	ClaimJob(key string, now time.Time) (bool, error)
+	// DeleteJobs forgets the jobs claimed before the time
+	DeleteJobs(before time.Time) error
+}
+
+// MaxWaitlist is the number of going participants allowed beyond the capacity
+const MaxWaitlist = 50
+
+// AddResult tells what AddIfAbsent has done with the participant
+type AddResult int
+
+const (
+	Added AddResult = iota
+	Waitlisted
+	Duplicate
+	Full
+)
+
+const (
+	schemeBolt   = "bolt"
+	schemeSQLite = "sqlite"
+	schemeMemory = "memory"
+)
+
+// Open opens the storage by the url like bolt://./bolt.db, sqlite://./tbot.db
+// or memory://. A path without a scheme is opened as a bolt file.
+func Open(url string) (Repository, error) {
+	scheme, path := splitURL(url)
+	switch scheme {
+	case schemeBolt:
+		s, err := NewBoltStorage(path)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case schemeSQLite:
+		s, err := NewSQLiteStorage(path)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case schemeMemory:
+		return NewMemoryStorage(), nil
+	}
+	return nil, errors.Errorf("unknown storage scheme %s", scheme)
+}
+
+// BoltPath returns the path of the bolt file from the storage url
+func BoltPath(url string) (string, error) {
+	scheme, path := splitURL(url)
+	if scheme != schemeBolt {
+		return "", errors.Errorf("%s is not a bolt storage", url)
+	}
+	return path, nil
+}
+
+func splitURL(url string) (scheme, path string) {
+	if i := strings.Index(url, "://"); i >= 0 {
+		return url[:i], url[i+len("://"):]
+	}
+	return schemeBolt, url
+}
+
+// sortByStatus orders the participants of the event: going, maybe, declined,
+// and by the time of the answer inside each group
+func sortByStatus(participants []Participant) {
+	sort.Slice(participants, func(i, j int) bool {
+		if oi, oj := statusOrder[participants[i].State()], statusOrder[participants[j].State()]; oi != oj {
+			return oi < oj
+		}
+		return participants[i].Time.Before(participants[j].Time)
+	})
+}
+
+func findByNumber(participants []Participant, number int) (participant Participant, err error) {
+	for i, p := range participants {
+		if number == i+1 {
+			return p, nil
+		}
+	}
+	return participant, NewError(ErrNotFound, "Participant with number %d not found", number)
+}
+
+func findByName(participants []Participant, name string) (participant Participant, err error) {
+	for _, p := range participants {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return participant, NewError(ErrNotFound, "Participant with name \"%s\" not found", name)
+}
+
+func findByLink(participants []Participant, name string) (participant Participant, err error) {
+	for _, p := range participants {
+		if p.Link() == name {
+			return p, nil
+		}
+	}
+	return participant, NewError(ErrNotFound, "Participant with link %s not found", name)
+}
+
+// addIfAbsent decides how the participant joins the list of the event.
+// It returns the stored participant and the one it replaces, e.g. the
+// unresolved participant added by link before the user answered.
+func addIfAbsent(participants []Participant, participant Participant, capacity int) (
+	stored Participant, replaced *Participant, result AddResult, err error) {
+
+	existing := findExisting(participants, participant)
+	if existing != nil {
+		// only the user can change the own answer
+		if participant.User.Type != UserTelegram ||
+			(existing.State() == participant.State() && !existing.IsUnresolved()) {
+			return *existing, nil, Duplicate, nil
+		}
+		if existing.State() == participant.State() {
+			participant.Time = existing.Time
+		}
+	}
+
+	after := []Participant{participant}
+	going := 0
+	for _, p := range participants {
+		if existing != nil && p.Id() == existing.Id() {
+			continue
+		}
+		after = append(after, p)
+		if p.IsGoing() {
+			going++
+		}
+	}
+
+	if participant.IsGoing() && (existing == nil || !existing.IsGoing()) && going >= capacity+MaxWaitlist {
+		return participant, nil, Full, NewError(ErrCapacity, "The list is full, maximum waitlist: %v", MaxWaitlist)
+	}
+
+	result = Added
+	sortByStatus(after)
+	for i, p := range after {
+		if p.Id() == participant.Id() && p.IsGoing() && i >= capacity {
+			result = Waitlisted
+		}
+	}
+	return participant, existing, result, nil
+}
+
+// findExisting returns the participant who is the same person: the same user,
+// the same link for the one added by link or the same name for the guest of the same owner
+func findExisting(participants []Participant, participant Participant) *Participant {
+	for i, p := range participants {
+		switch {
+		case p.Id() == participant.Id():
+		case participant.User.Type == UserGuest && p.Name() == participant.Name() && p.ownerUid() == participant.ownerUid():
+		case participant.User.Type != UserGuest && participant.User.UserName != "" && p.Link() == participant.Link():
+		default:
+			continue
+		}
+		return &participants[i]
+	}
+	return nil
+}
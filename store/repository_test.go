@@ -0,0 +1,338 @@
+package store
+
+import (
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// backends opens every storage, so the same behaviour is checked on each of them
+func backends(t *testing.T) map[string]Repository {
+	dir := t.TempDir()
+	res := map[string]Repository{}
+	for name, url := range map[string]string{
+		"bolt":   "bolt://" + filepath.Join(dir, "bolt.db"),
+		"sqlite": "sqlite://" + filepath.Join(dir, "tbot.db"),
+		"memory": "memory://",
+	} {
+		s, err := Open(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		res[name] = s
+	}
+	return res
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open("redis://localhost"); err == nil {
+		t.Error("unknown scheme is opened")
+	}
+	s, err := Open(filepath.Join(t.TempDir(), "bolt.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+	if _, ok := s.(*BoltStorage); !ok {
+		t.Errorf("plain path is opened as %T", s)
+	}
+}
+
+func TestParticipants(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			event := Event{ChatId: 1}
+			now := time.Now()
+			s.Create(Participant{User: User{Id: "1", FirstName: "a"}, ChatId: 1, Time: now, Status: StatusMaybe})
+			s.Create(Participant{User: User{Id: "2", FirstName: "b"}, ChatId: 1, Time: now.Add(time.Second)})
+			s.Create(Participant{User: User{Id: "3", FirstName: "c"}, ChatId: 1, EventId: 5, Time: now})
+			s.Create(Participant{User: User{Id: "4", FirstName: "d"}, ChatId: 2, Time: now})
+
+			participants, err := s.FindByEvent(event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(participants) != 2 || participants[0].User.Id != "2" || participants[1].User.Id != "1" {
+				t.Fatalf("participants %v, going first", participants)
+			}
+			if p, err := s.FindByNumber(2, event); err != nil || p.User.Id != "1" {
+				t.Errorf("number 2 is %v, %v", p, err)
+			}
+			if _, err := s.FindByNumber(3, event); !errors.Is(err, ErrNotFound) {
+				t.Errorf("number 3 is found: %v", err)
+			}
+			if _, err := s.Find(Participant{User: User{Id: "3"}, ChatId: 1}); !errors.Is(err, ErrNotFound) {
+				t.Errorf("participant of another event is found: %v", err)
+			}
+			if all, _ := s.FindAll(); len(all) != 4 {
+				t.Errorf("%d participants in all chats", len(all))
+			}
+
+			if err := s.Delete(participants[0]); err != nil {
+				t.Fatal(err)
+			}
+			if n, _ := s.CountByEvent(event); n != 1 {
+				t.Errorf("%d participants after delete", n)
+			}
+			if err := s.DeleteByEvent(event); err != nil {
+				t.Fatal(err)
+			}
+			if n, _ := s.CountByEvent(event); n != 0 {
+				t.Errorf("%d participants after delete by event", n)
+			}
+		})
+	}
+}
+
+func TestAddIfAbsent(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			add := func(user User, status Status) AddResult {
+				_, result, _ := s.AddIfAbsent(Participant{User: user, ChatId: 1, Time: time.Now(), Status: status}, 1)
+				return result
+			}
+			ann := User{Id: "1", UserName: "ann", Type: UserTelegram}
+
+			if r := add(User{UserName: "ann", Type: UserUnresolved}, ""); r != Added {
+				t.Errorf("add by link: %v", r)
+			}
+			if r := add(User{UserName: "Ann", Type: UserGuest}, ""); r != Waitlisted {
+				t.Errorf("add guest: %v", r)
+			}
+			if r := add(User{UserName: "Ann", Type: UserGuest}, ""); r != Duplicate {
+				t.Errorf("add guest twice: %v", r)
+			}
+			smith := User{Id: "2", UserName: "smith", Type: UserTelegram}
+			guest := Participant{User: NewGuest(smith, "Ann"), Owner: &smith, ChatId: 1, Time: time.Now()}
+			if _, r, _ := s.AddIfAbsent(guest, 1); r != Waitlisted {
+				t.Errorf("add guest of smith with the same name: %v", r)
+			}
+			if stored, r, _ := s.AddIfAbsent(guest, 1); r != Duplicate || !stored.IsGuestOf(smith) {
+				t.Errorf("add guest of smith twice: %v, %+v", r, stored)
+			}
+			if err := s.Delete(guest); err != nil {
+				t.Fatal(err)
+			}
+			if r := add(ann, StatusGoing); r != Added {
+				t.Errorf("resolve link: %v", r)
+			}
+			if r := add(ann, StatusGoing); r != Duplicate {
+				t.Errorf("answer twice: %v", r)
+			}
+			if r := add(User{UserName: "ann", Type: UserUnresolved}, ""); r != Duplicate {
+				t.Errorf("add answered user by link: %v", r)
+			}
+			if r := add(ann, StatusMaybe); r != Added {
+				t.Errorf("change answer: %v", r)
+			}
+			if n, _ := s.CountByEvent(Event{ChatId: 1}); n != 2 {
+				t.Errorf("%d participants", n)
+			}
+		})
+	}
+}
+
+func TestAddIfAbsentConcurrently(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for i := 0; i < MaxWaitlist+10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					user := User{Id: strconv.Itoa(i), Type: UserTelegram}
+					_, _, _ = s.AddIfAbsent(Participant{User: user, ChatId: 1, Time: time.Now()}, 1)
+				}(i)
+			}
+			wg.Wait()
+			if n, _ := s.CountByEvent(Event{ChatId: 1}); n != MaxWaitlist+1 {
+				t.Errorf("%d participants, want %d", n, MaxWaitlist+1)
+			}
+			_, result, err := s.AddIfAbsent(Participant{User: User{Id: "last", Type: UserTelegram}, ChatId: 1}, 1)
+			if result != Full || !errors.Is(err, ErrCapacity) {
+				t.Errorf("add to the full list: %v, %v", result, err)
+			}
+		})
+	}
+}
+
+func TestEvents(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			if e, err := s.ActiveEvent(1); err != nil || !e.IsDefault() {
+				t.Fatalf("active event %v, %v", e, err)
+			}
+			first, _ := s.CreateEvent(1, "First")
+			second, _ := s.CreateEvent(1, "Second")
+			if first.Id != 1 || second.Id != 2 {
+				t.Errorf("event ids %d, %d", first.Id, second.Id)
+			}
+			if e, _ := s.ActiveEvent(1); e.Id != second.Id {
+				t.Errorf("active event %d after create", e.Id)
+			}
+			if _, err := s.SwitchEvent(1, 7); !errors.Is(err, ErrNotFound) {
+				t.Error("switched to unknown event")
+			}
+			if _, err := s.SwitchEvent(1, 0); err != nil {
+				t.Fatal(err)
+			}
+			if e, _ := s.ActiveEvent(1); !e.IsDefault() {
+				t.Errorf("active event %d after switch", e.Id)
+			}
+
+			first.Location = "Arena"
+			if err := s.SaveEvent(first); err != nil {
+				t.Fatal(err)
+			}
+			if e, _ := s.FindEvent(1, first.Id); e.Location != "Arena" {
+				t.Errorf("event is not saved: %v", e)
+			}
+			if events, _ := s.FindEvents(1); len(events) != 2 {
+				t.Errorf("%d events", len(events))
+			}
+		})
+	}
+}
+
+func TestArchiveAndJobs(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			event := Event{ChatId: 1, Title: "Match"}
+			s.Create(Participant{User: User{Id: "1"}, ChatId: 1, Time: time.Now()})
+			archived, err := s.ArchiveEvent(event, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if empty, err := s.ArchiveEvent(event, time.Time{}); err != nil || empty.Id != 0 {
+				t.Fatalf("empty list is archived: %v, %v", empty, err)
+			}
+			archive, _ := s.FindArchive(1)
+			if len(archive) != 1 || archive[0].Event.Title != "Match" || len(archive[0].Participants) != 1 {
+				t.Errorf("archive %v", archive)
+			}
+			if n, _ := s.CountByEvent(event); n != 0 {
+				t.Error("archived participants are left in the list")
+			}
+			if err = s.RestoreArchive(1, archived.Id); err != nil {
+				t.Fatal(err)
+			}
+			if n, _ := s.CountByEvent(event); n != 1 {
+				t.Errorf("%d participants restored", n)
+			}
+			if err = s.RestoreArchive(1, archived.Id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("archive is restored twice: %v", err)
+			}
+			again, _ := s.ArchiveEvent(event, time.Time{})
+			if again.Id == archived.Id {
+				t.Errorf("archive id %d of the restored archive is reused", again.Id)
+			}
+			if err = s.RestoreArchive(1, again.Id); err != nil {
+				t.Fatal(err)
+			}
+
+			// the event changed after it was read keeps the changes when it is reopened
+			stale, _ := s.CreateEvent(1, "Match")
+			changed := stale
+			changed.Title = "Final"
+			changed.MessageId = 7
+			if err = s.SaveEvent(changed); err != nil {
+				t.Fatal(err)
+			}
+			start := time.Date(2020, 5, 24, 19, 0, 0, 0, time.UTC)
+			if _, err = s.ArchiveEvent(stale, start); err != nil {
+				t.Fatal(err)
+			}
+			if reopened, _ := s.FindEvent(1, stale.Id); reopened.Title != "Final" || !reopened.Start.Equal(start) ||
+				reopened.MessageId != 0 {
+				t.Errorf("reopened event %+v", reopened)
+			}
+
+			now := time.Now()
+			if claimed, _ := s.ClaimJob("job", now); !claimed {
+				t.Error("job is not claimed")
+			}
+			if claimed, _ := s.ClaimJob("job", now); claimed {
+				t.Error("job is claimed twice")
+			}
+			if err := s.DeleteJobs(now.Add(time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			if claimed, _ := s.ClaimJob("job", now); !claimed {
+				t.Error("deleted job is not claimed again")
+			}
+		})
+	}
+}
+
+func TestSettings(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			settings, err := s.FindSettings(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if settings.ChatId != 1 || !settings.AdminOnly("reset") || settings.AdminOnly("title") {
+				t.Errorf("default settings %+v", settings)
+			}
+
+			settings.AdminCommands = []string{}
+			if err = s.SaveSettings(settings); err != nil {
+				t.Fatal(err)
+			}
+			settings, _ = s.FindSettings(1)
+			if settings.AdminOnly("reset") {
+				t.Errorf("cleared admin commands are the defaults: %+v", settings)
+			}
+			if other, _ := s.FindSettings(2); !other.AdminOnly("reset") {
+				t.Errorf("settings of another chat %+v", other)
+			}
+
+			settings.SetAdminOnly("reset", true)
+			settings.Capacity, settings.Timezone, settings.NoGuests, settings.Format = 12, "Europe/Minsk", true, FormatCompact
+			if err = s.SaveSettings(settings); err != nil {
+				t.Fatal(err)
+			}
+			saved, _ := s.FindSettings(1)
+			if !saved.AdminOnly("reset") || saved.AdminOnly("rm") || saved.Capacity != 12 ||
+				saved.Timezone != "Europe/Minsk" || !saved.NoGuests || saved.Format != FormatCompact {
+				t.Errorf("saved settings %+v", saved)
+			}
+		})
+	}
+}
+
+func TestJournal(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.LastOperation(1); !errors.Is(err, ErrNotFound) {
+				t.Errorf("empty journal: %v", err)
+			}
+			for i := 0; i < JournalLength+2; i++ {
+				if _, err := s.PushOperation(Operation{ChatId: 1, Kind: OperationRemove}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			last, err := s.LastOperation(1)
+			if err != nil || last.Id != JournalLength+2 || last.Kind != OperationRemove {
+				t.Fatalf("last operation %+v, %v", last, err)
+			}
+			if err = s.DeleteOperation(last); err != nil {
+				t.Fatal(err)
+			}
+			if err = s.DeleteOperation(last); !errors.Is(err, ErrNotFound) {
+				t.Errorf("operation is deleted twice: %v", err)
+			}
+			if op, _ := s.LastOperation(1); op.Id != JournalLength+1 {
+				t.Errorf("last operation %d after delete", op.Id)
+			}
+			if _, err = s.LastOperation(2); !errors.Is(err, ErrNotFound) {
+				t.Errorf("journal of another chat: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"log"
+	"time"
+)
+
+// DefaultAdminCommands are admin-only in the chats without settings
+var DefaultAdminCommands = []string{"reset", "rm"}
+
+type ListFormat string
+
+const (
+	// FormatFull shows the event details and the totals above the list
+	FormatFull ListFormat = "full"
+	// FormatCompact shows the title and the participants only
+	FormatCompact ListFormat = "compact"
+)
+
+// Settings keeps the options of the chat, the zero values are the defaults
+type Settings struct {
+	ChatId int64
+	// AdminCommands are the commands only the administrators can use, the
+	// defaults if nil. For add and rm only adding or removing others is limited.
+	AdminCommands []string
+	// Language is the code of the language of the bot, the default if empty
+	Language string
+	// Capacity is the size of the lists without their own capacity
+	Capacity int
+	// Timezone is the IANA name of the time zone of the chat, the local one if empty
+	Timezone string
+	// NoGuests forbids adding the participants by name and the plus-ones
+	NoGuests bool
+	// GuestQuota is the number of the guests a member can bring, not limited if 0
+	GuestQuota int
+	// Format is the layout of the list message, FormatFull if empty
+	Format ListFormat
+}
+
+// AdminOnly tells if the command is limited to the administrators
+func (s Settings) AdminOnly(command string) bool {
+	commands := s.AdminCommands
+	if commands == nil {
+		commands = DefaultAdminCommands
+	}
+	for _, c := range commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// Location returns the time zone of the chat, the local one if not set
+func (s Settings) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		log.Print(err)
+		return time.Local
+	}
+	return location
+}
+
+// SetAdminOnly adds the command to the admin-only ones or removes it
+func (s *Settings) SetAdminOnly(command string, on bool) {
+	if s.AdminOnly(command) == on {
+		return
+	}
+	commands := []string{}
+	if s.AdminCommands == nil {
+		s.AdminCommands = DefaultAdminCommands
+	}
+	for _, c := range s.AdminCommands {
+		if c != command {
+			commands = append(commands, c)
+		}
+	}
+	if on {
+		commands = append(commands, command)
+	}
+	s.AdminCommands = commands
+}
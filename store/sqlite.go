@@ -0,0 +1,599 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// The rows keep the same JSON as the bolt values. The other
+// columns are only the keys the queries look up by.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS participants (
+		chat_id INTEGER NOT NULL,
+		id TEXT NOT NULL,
+		event_id INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (chat_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS events (
+		chat_id INTEGER NOT NULL,
+		id INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (chat_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS members (
+		chat_id INTEGER NOT NULL,
+		id TEXT NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (chat_id, id)
+	)`,
+	// the ids of the restored archives are not reused, like the bolt sequences
+	`CREATE TABLE IF NOT EXISTS archive (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		chat_id INTEGER NOT NULL,
+		data TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS archive_chat_id ON archive (chat_id)`,
+	`CREATE TABLE IF NOT EXISTS settings (
+		chat_id INTEGER PRIMARY KEY,
+		data TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS journal (
+		chat_id INTEGER PRIMARY KEY,
+		data TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS jobs (
+		key TEXT PRIMARY KEY,
+		claimed INTEGER NOT NULL
+	)`,
+}
+
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+func NewSQLiteStorage(storePath string) (*SQLiteStorage, error) {
+	db, err := sql.Open("sqlite3", storePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make sqlite for %s", storePath)
+	}
+	// sqlite allows a single writer, so one connection avoids "database is locked"
+	db.SetMaxOpenConns(1)
+
+	for _, query := range sqliteSchema {
+		if _, err = db.Exec(query); err != nil {
+			_ = db.Close()
+			return nil, errors.Wrapf(err, "failed to create tables in %s", storePath)
+		}
+	}
+	log.Printf("Storage opened in %s", storePath)
+
+	return &SQLiteStorage{
+		db: db,
+	}, nil
+}
+
+func (s *SQLiteStorage) Close() error {
+	if err := s.db.Close(); err != nil {
+		return errors.Wrap(err, "Failed to close storage")
+	}
+	log.Print("Storage closed")
+	return nil
+}
+
+func (s *SQLiteStorage) Create(participant Participant) (Participant, error) {
+	data, err := json.Marshal(participant)
+	if err == nil {
+		_, err = s.db.Exec(`INSERT OR REPLACE INTO participants (chat_id, id, event_id, data) VALUES (?, ?, ?, ?)`,
+			participant.ChatId, participant.Id(), participant.EventId, data)
+	}
+	return participant, errors.Wrapf(err, "Failed to save participant")
+}
+
+func (s *SQLiteStorage) Delete(participant Participant) error {
+	_, err := s.db.Exec(`DELETE FROM participants WHERE chat_id = ? AND id = ?`, participant.ChatId, participant.Id())
+	return errors.Wrapf(err, "Failed to delete participant")
+}
+
+func (s *SQLiteStorage) DeleteByEvent(event Event) error {
+	_, err := s.db.Exec(`DELETE FROM participants WHERE chat_id = ? AND event_id = ?`, event.ChatId, event.Id)
+	return errors.Wrapf(err, "Failed to delete participants")
+}
+
+func (s *SQLiteStorage) Find(p Participant) (participant Participant, err error) {
+	var data []byte
+	err = s.db.QueryRow(`SELECT data FROM participants WHERE chat_id = ? AND id = ?`, p.ChatId, p.Id()).Scan(&data)
+	if err == sql.ErrNoRows {
+		return participant, NewError(ErrNotFound, "Participant %s not found", p.Id())
+	}
+	if err != nil {
+		return participant, errors.Wrapf(err, "failed to find %s", p.Id())
+	}
+	return participant, errors.Wrap(json.Unmarshal(data, &participant), "failed to unmarshal")
+}
+
+func (s *SQLiteStorage) FindByNumber(number int, event Event) (Participant, error) {
+	participants, err := s.FindByEvent(event)
+	if err != nil {
+		return Participant{}, err
+	}
+	return findByNumber(participants, number)
+}
+
+func (s *SQLiteStorage) FindByName(name string, event Event) (Participant, error) {
+	participants, err := s.FindByEvent(event)
+	if err != nil {
+		return Participant{}, err
+	}
+	return findByName(participants, name)
+}
+
+func (s *SQLiteStorage) FindByLink(name string, event Event) (Participant, error) {
+	participants, err := s.FindByEvent(event)
+	if err != nil {
+		return Participant{}, err
+	}
+	return findByLink(participants, name)
+}
+
+func (s *SQLiteStorage) FindAll() (participants []Participant, err error) {
+	participants = []Participant{}
+	err = s.query(`SELECT data FROM participants`, nil, func(data []byte) error {
+		participant := Participant{}
+		if e := json.Unmarshal(data, &participant); e != nil {
+			return errors.Wrap(e, "failed to unmarshal")
+		}
+		participants = append(participants, participant)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to find participants")
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].Time.After(participants[j].Time)
+	})
+	return participants, nil
+}
+
+func (s *SQLiteStorage) FindByEvent(event Event) ([]Participant, error) {
+	participants, err := s.participants(s.db, event)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to find participants")
+	}
+	sortByStatus(participants)
+	return participants, nil
+}
+
+func (s *SQLiteStorage) CountByEvent(event Event) (int, error) {
+	participants, err := s.FindByEvent(event)
+	return len(participants), err
+}
+
+// AddIfAbsent adds the participant unless the same person is already
+// in the list. The capacity is checked in the same transaction.
+func (s *SQLiteStorage) AddIfAbsent(participant Participant, capacity int) (stored Participant, result AddResult, err error) {
+	err = s.update(func(tx *sql.Tx) error {
+		participants, err := s.participants(tx, Event{Id: participant.EventId, ChatId: participant.ChatId})
+		if err != nil {
+			return err
+		}
+
+		var replaced *Participant
+		stored, replaced, result, err = addIfAbsent(participants, participant, capacity)
+		if err != nil || result == Duplicate {
+			return err
+		}
+		if replaced != nil {
+			if _, err = tx.Exec(`DELETE FROM participants WHERE chat_id = ? AND id = ?`, replaced.ChatId, replaced.Id()); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(stored)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal")
+		}
+		_, err = tx.Exec(`INSERT OR REPLACE INTO participants (chat_id, id, event_id, data) VALUES (?, ?, ?, ?)`,
+			stored.ChatId, stored.Id(), stored.EventId, data)
+		return err
+	})
+	return stored, result, errors.Wrapf(err, "Failed to add participant")
+}
+
+func (s *SQLiteStorage) participants(q querier, event Event) (participants []Participant, err error) {
+	err = s.queryWith(q, `SELECT data FROM participants WHERE chat_id = ? AND event_id = ?`,
+		[]interface{}{event.ChatId, event.Id}, func(data []byte) error {
+			participant := Participant{}
+			if e := json.Unmarshal(data, &participant); e != nil {
+				return errors.Wrap(e, "failed to unmarshal")
+			}
+			participants = append(participants, participant)
+			return nil
+		})
+	return participants, err
+}
+
+func (s *SQLiteStorage) CreateEvent(chatId int64, title string) (event Event, err error) {
+	err = s.update(func(tx *sql.Tx) error {
+		if e := s.deactivateEvents(tx, chatId); e != nil {
+			return e
+		}
+
+		var id int
+		if e := tx.QueryRow(`SELECT COALESCE(MAX(id), 0) + 1 FROM events WHERE chat_id = ?`, chatId).Scan(&id); e != nil {
+			return errors.Wrap(e, "failed to get next event id")
+		}
+
+		event = Event{
+			Id:     id,
+			ChatId: chatId,
+			Title:  title,
+			Active: true,
+			Time:   time.Now(),
+		}
+		return s.saveEvent(tx, event)
+	})
+	return event, errors.Wrapf(err, "Failed to create event")
+}
+
+func (s *SQLiteStorage) FindEvents(chatId int64) (events []Event, err error) {
+	return s.events(s.db, `SELECT data FROM events WHERE chat_id = ? ORDER BY id`, chatId)
+}
+
+func (s *SQLiteStorage) FindAllEvents() (events []Event, err error) {
+	return s.events(s.db, `SELECT data FROM events`)
+}
+
+func (s *SQLiteStorage) FindEvent(chatId int64, eventId int) (event Event, err error) {
+	return s.findEvent(s.db, chatId, eventId)
+}
+
+func (s *SQLiteStorage) ActiveEvent(chatId int64) (Event, error) {
+	events, err := s.FindEvents(chatId)
+	if err != nil {
+		return Event{}, err
+	}
+	for _, e := range events {
+		if e.Active {
+			return e, nil
+		}
+	}
+	return s.FindEvent(chatId, 0)
+}
+
+func (s *SQLiteStorage) SaveEvent(event Event) error {
+	return errors.Wrapf(s.saveEvent(s.db, event), "Failed to save event")
+}
+
+func (s *SQLiteStorage) SwitchEvent(chatId int64, eventId int) (event Event, err error) {
+	err = s.update(func(tx *sql.Tx) (err error) {
+		if event, err = s.findEvent(tx, chatId, eventId); err != nil {
+			return err
+		}
+		if err = s.deactivateEvents(tx, chatId); err != nil {
+			return err
+		}
+		if event.IsDefault() {
+			return nil
+		}
+		event.Active = true
+		return s.saveEvent(tx, event)
+	})
+	return event, err
+}
+
+func (s *SQLiteStorage) findEvent(q querier, chatId int64, eventId int) (event Event, err error) {
+	event = Event{ChatId: chatId}
+	var data []byte
+	err = q.QueryRow(`SELECT data FROM events WHERE chat_id = ? AND id = ?`, chatId, eventId).Scan(&data)
+	if err == sql.ErrNoRows {
+		if eventId != 0 {
+			return event, NewError(ErrNotFound, "Event %d not found", eventId)
+		}
+		return event, nil
+	}
+	if err != nil {
+		return event, errors.Wrapf(err, "failed to find event %d", eventId)
+	}
+	return event, errors.Wrap(json.Unmarshal(data, &event), "failed to unmarshal")
+}
+
+func (s *SQLiteStorage) events(q querier, query string, args ...interface{}) (events []Event, err error) {
+	err = s.queryWith(q, query, args, func(data []byte) error {
+		event := Event{}
+		if e := json.Unmarshal(data, &event); e != nil {
+			return errors.Wrap(e, "failed to unmarshal")
+		}
+		events = append(events, event)
+		return nil
+	})
+	return events, err
+}
+
+func (s *SQLiteStorage) saveEvent(q querier, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal")
+	}
+	_, err = q.Exec(`INSERT OR REPLACE INTO events (chat_id, id, data) VALUES (?, ?, ?)`, event.ChatId, event.Id, data)
+	return err
+}
+
+func (s *SQLiteStorage) deactivateEvents(tx *sql.Tx, chatId int64) error {
+	events, err := s.events(tx, `SELECT data FROM events WHERE chat_id = ?`, chatId)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if !event.Active {
+			continue
+		}
+		event.Active = false
+		if err = s.saveEvent(tx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) SaveMember(member Member) error {
+	data, err := json.Marshal(member)
+	if err != nil {
+		return errors.Wrap(err, "Failed to save member")
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO members (chat_id, id, data) VALUES (?, ?, ?)`, member.ChatId, member.Id(), data)
+	return errors.Wrapf(err, "Failed to save member")
+}
+
+func (s *SQLiteStorage) DeleteMember(member Member) error {
+	_, err := s.db.Exec(`DELETE FROM members WHERE chat_id = ? AND id = ?`, member.ChatId, member.Id())
+	return errors.Wrapf(err, "Failed to delete member")
+}
+
+func (s *SQLiteStorage) FindMembers(chatId int64) (members []Member, err error) {
+	err = s.query(`SELECT data FROM members WHERE chat_id = ?`, []interface{}{chatId}, func(data []byte) error {
+		member := Member{}
+		if e := json.Unmarshal(data, &member); e != nil {
+			return errors.Wrap(e, "failed to unmarshal")
+		}
+		members = append(members, member)
+		return nil
+	})
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Time.Before(members[j].Time)
+	})
+	return members, err
+}
+
+// FindSettings returns the settings of the chat, the empty ones if not saved
+func (s *SQLiteStorage) FindSettings(chatId int64) (settings Settings, err error) {
+	settings.ChatId = chatId
+	err = s.query(`SELECT data FROM settings WHERE chat_id = ?`, []interface{}{chatId}, func(data []byte) error {
+		return errors.Wrap(json.Unmarshal(data, &settings), "failed to unmarshal")
+	})
+	return settings, errors.Wrap(err, "Failed to find settings")
+}
+
+func (s *SQLiteStorage) SaveSettings(settings Settings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return errors.Wrap(err, "Failed to save settings")
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO settings (chat_id, data) VALUES (?, ?)`, settings.ChatId, data)
+	return errors.Wrap(err, "Failed to save settings")
+}
+
+func (s *SQLiteStorage) ArchiveEvent(event Event, start time.Time) (archive Archive, err error) {
+	err = s.update(func(tx *sql.Tx) error {
+		participants, err := s.participants(tx, event)
+		if err != nil {
+			return err
+		}
+
+		if len(participants) > 0 {
+			res, err := tx.Exec(`INSERT INTO archive (chat_id, data) VALUES (?, '')`, event.ChatId)
+			if err != nil {
+				return err
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return errors.Wrap(err, "failed to get next archive id")
+			}
+			archive = Archive{
+				Id:           int(id),
+				Event:        event,
+				Participants: participants,
+				Time:         time.Now(),
+			}
+			data, err := json.Marshal(archive)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal")
+			}
+			if _, err = tx.Exec(`UPDATE archive SET data = ? WHERE id = ?`, data, id); err != nil {
+				return err
+			}
+		}
+
+		if _, err = tx.Exec(`DELETE FROM participants WHERE chat_id = ? AND event_id = ?`, event.ChatId, event.Id); err != nil {
+			return err
+		}
+
+		if start.IsZero() {
+			return nil
+		}
+		reopened, err := s.findEvent(tx, event.ChatId, event.Id)
+		if err != nil {
+			return err
+		}
+		reopened.Start = start
+		reopened.MessageId = 0
+		return s.saveEvent(tx, reopened)
+	})
+	return archive, errors.Wrapf(err, "Failed to archive event")
+}
+
+func (s *SQLiteStorage) RestoreArchive(chatId int64, archiveId int) error {
+	err := s.update(func(tx *sql.Tx) error {
+		var archive *Archive
+		err := s.queryWith(tx, `SELECT data FROM archive WHERE chat_id = ? AND id = ?`, []interface{}{chatId, archiveId},
+			func(data []byte) error {
+				archive = &Archive{}
+				return errors.Wrap(json.Unmarshal(data, archive), "failed to unmarshal")
+			})
+		if err != nil {
+			return err
+		}
+		if archive == nil {
+			return NewError(ErrNotFound, "Archive %d not found", archiveId)
+		}
+
+		for _, p := range archive.Participants {
+			data, err := json.Marshal(p)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal")
+			}
+			if _, err = tx.Exec(`INSERT OR REPLACE INTO participants (chat_id, id, event_id, data) VALUES (?, ?, ?, ?)`,
+				p.ChatId, p.Id(), p.EventId, data); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(`DELETE FROM archive WHERE chat_id = ? AND id = ?`, chatId, archiveId)
+		return err
+	})
+	return errors.Wrap(err, "Failed to restore archive")
+}
+
+func (s *SQLiteStorage) PushOperation(op Operation) (saved Operation, err error) {
+	err = s.update(func(tx *sql.Tx) error {
+		journal, err := s.journal(tx, op.ChatId)
+		if err != nil {
+			return err
+		}
+		journal, saved = pushOperation(journal, op)
+		return s.saveJournal(tx, op.ChatId, journal)
+	})
+	return saved, errors.Wrap(err, "Failed to save operation")
+}
+
+func (s *SQLiteStorage) LastOperation(chatId int64) (Operation, error) {
+	journal, err := s.journal(s.db, chatId)
+	if err != nil {
+		return Operation{}, errors.Wrap(err, "Failed to find operation")
+	}
+	return lastOperation(journal)
+}
+
+func (s *SQLiteStorage) DeleteOperation(op Operation) error {
+	err := s.update(func(tx *sql.Tx) error {
+		journal, err := s.journal(tx, op.ChatId)
+		if err != nil {
+			return err
+		}
+		if journal, err = removeOperation(journal, op.Id); err != nil {
+			return err
+		}
+		return s.saveJournal(tx, op.ChatId, journal)
+	})
+	return errors.Wrap(err, "Failed to delete operation")
+}
+
+func (s *SQLiteStorage) journal(q querier, chatId int64) (journal []Operation, err error) {
+	err = s.queryWith(q, `SELECT data FROM journal WHERE chat_id = ?`, []interface{}{chatId}, func(data []byte) error {
+		return errors.Wrap(json.Unmarshal(data, &journal), "failed to unmarshal")
+	})
+	return journal, err
+}
+
+func (s *SQLiteStorage) saveJournal(tx *sql.Tx, chatId int64, journal []Operation) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal")
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO journal (chat_id, data) VALUES (?, ?)`, chatId, data)
+	return err
+}
+
+func (s *SQLiteStorage) FindArchive(chatId int64) ([]Archive, error) {
+	return s.archive(`SELECT data FROM archive WHERE chat_id = ? ORDER BY id`, chatId)
+}
+
+func (s *SQLiteStorage) FindAllArchive() ([]Archive, error) {
+	archive, err := s.archive(`SELECT data FROM archive`)
+	sort.Slice(archive, func(i, j int) bool {
+		return archive[i].Time.Before(archive[j].Time)
+	})
+	return archive, err
+}
+
+func (s *SQLiteStorage) archive(query string, args ...interface{}) (archive []Archive, err error) {
+	err = s.query(query, args, func(data []byte) error {
+		a := Archive{}
+		if e := json.Unmarshal(data, &a); e != nil {
+			return errors.Wrap(e, "failed to unmarshal")
+		}
+		archive = append(archive, a)
+		return nil
+	})
+	return archive, err
+}
+
+func (s *SQLiteStorage) ClaimJob(key string, now time.Time) (claimed bool, err error) {
+	res, err := s.db.Exec(`INSERT OR IGNORE INTO jobs (key, claimed) VALUES (?, ?)`, key, now.UnixNano())
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed to claim job %s", key)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, errors.Wrapf(err, "Failed to claim job %s", key)
+}
+
+func (s *SQLiteStorage) DeleteJobs(before time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM jobs WHERE claimed < ?`, before.UnixNano())
+	return errors.Wrapf(err, "Failed to delete jobs")
+}
+
+// querier is either the database or the transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (s *SQLiteStorage) update(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStorage) query(query string, args []interface{}, fn func(data []byte) error) error {
+	return s.queryWith(s.db, query, args, fn)
+}
+
+// queryWith calls fn with the data column of every row
+func (s *SQLiteStorage) queryWith(q querier, query string, args []interface{}, fn func(data []byte) error) error {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data []byte
+		if err = rows.Scan(&data); err != nil {
+			return err
+		}
+		if err = fn(data); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
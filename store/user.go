@@ -22,6 +22,12 @@ const (
 	UserGuest      UserType = "guest"
 )
 
+// NewGuest returns the guest the owner brings, the guests
+// of different owners can have the same name
+func NewGuest(owner User, name string) User {
+	return User{Id: owner.Uid() + "/" + name, UserName: name, Type: UserGuest}
+}
+
 func (u User) Uid() string {
 	id := u.Id
 	if id == "" {
@@ -2,8 +2,13 @@ package main
 
 import (
 	"flag"
+	"github.com/pkg/errors"
+	"github.com/taras-by/tbot/store"
+	tlg "github.com/taras-by/tbot/telegram"
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 type command struct {
@@ -19,14 +24,25 @@ var (
 )
 
 const (
-	defaultStorePath = "./bolt.db"
+	defaultStorePath  = "./bolt.db"
+	defaultListen     = ":8443"
+	schedulerInterval = time.Minute
+	backupInterval    = 24 * time.Hour
+	backupKeep        = 7
+	modePolling       = "polling"
+	modeWebhook       = "webhook"
 )
 
 func main() {
 
 	commands := map[string]command{
-		"run":  runCmd(),
-		"show": showCmd(),
+		"run":     runCmd(),
+		"show":    showCmd(),
+		"migrate": migrateCmd(),
+		"export":  exportCmd(),
+		"import":  importCmd(),
+		"backup":  backupCmd(),
+		"restore": restoreCmd(),
 	}
 
 	fs := flag.NewFlagSet("tbot", flag.ExitOnError)
@@ -42,17 +58,34 @@ func main() {
 		os.Exit(1)
 	}
 
+	cmd, ok := commands[args[0]]
+	if !ok {
+		log.Fatalf("Unknown command: %s", args[0])
+	}
+
 	fs.StringVar(&Opts.TelegramToken, "telegram-token", os.Getenv("TELEGRAM_TOKEN"), "Token for Telegram")
-	fs.StringVar(&Opts.StorePath, "store-path", getEnv("STORE_PATH", defaultStorePath), "Path for storage")
+	fs.StringVar(&Opts.StorePath, "store-path", getEnv("STORE_PATH", defaultStorePath), "Storage url: bolt://path, sqlite://path or memory://. A plain path is a bolt file")
+	fs.StringVar(&Opts.Mode, "mode", getEnv("MODE", modePolling), "Updates mode: polling or webhook")
+	fs.StringVar(&Opts.Listen, "listen", getEnv("LISTEN", defaultListen), "Address for webhook server")
+	fs.StringVar(&Opts.PublicURL, "public-url", os.Getenv("PUBLIC_URL"), "Public URL of webhook")
+	fs.StringVar(&Opts.WebhookSecret, "webhook-secret", os.Getenv("WEBHOOK_SECRET"), "Secret token of webhook")
+	fs.StringVar(&Opts.Owners, "owners", os.Getenv("OWNERS"), "Comma separated Telegram user ids allowed to use the admin-only commands in any chat")
+	fs.StringVar(&Opts.BackupDir, "backup-dir", os.Getenv("BACKUP_DIR"), "Directory for periodic backups, none by default")
+	fs.DurationVar(&Opts.BackupInterval, "backup-interval", getEnvDuration("BACKUP_INTERVAL", backupInterval), "Interval of periodic backups")
+	fs.IntVar(&Opts.BackupKeep, "backup-keep", getEnvInt("BACKUP_KEEP", backupKeep), "Number of periodic backups to keep")
+
+	if cmd.fs != nil {
+		cmd.fs.VisitAll(func(f *flag.Flag) {
+			fs.Var(f.Value, f.Name, f.Usage)
+		})
+	}
 
 	err = fs.Parse(os.Args[2:])
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if cmd, ok := commands[args[0]]; !ok {
-		log.Fatalf("Unknown command: %s", args[0])
-	} else if err := cmd.fn(args[1:]); err != nil {
+	if err := cmd.fn(fs.Args()); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -63,11 +96,73 @@ func showCmd() command {
 	}}
 }
 
+func migrateCmd() command {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Show the migrations without applying them")
+	return command{fs: fs, fn: func([]string) error {
+		return migrate(*dryRun)
+	}}
+}
+
+func exportCmd() command {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	chatId := fs.Int64("chat", 0, "Chat id")
+	format := fs.String("format", store.FormatJSON, "Format: json or csv")
+	return command{fs: fs, fn: func(args []string) error {
+		return export(*chatId, *format, args)
+	}}
+}
+
+func importCmd() command {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	chatId := fs.Int64("chat", 0, "Chat id, the one from the file by default")
+	format := fs.String("format", "", "Format: json or csv, the file extension by default")
+	return command{fs: fs, fn: func(args []string) error {
+		return importFile(*chatId, *format, args)
+	}}
+}
+
+func backupCmd() command {
+	return command{fn: backup}
+}
+
+func restoreCmd() command {
+	return command{fn: restore}
+}
+
 func runCmd() command {
 	return command{fn: func([]string) error {
+		signals, stopSignals := backupSignals()
+		defer stopSignals()
 		a := newApp()
 		defer a.Close()
+		if err := store.CheckSchema(a.storage); err != nil {
+			return err
+		}
 		s := a.makeBotService()
+
+		stop := make(chan struct{})
+		defer close(stop)
+		scheduler := tlg.Scheduler{Handler: s.Handler, Interval: schedulerInterval}
+		go scheduler.Run(stop)
+		if a.options.BackupDir != "" && (a.options.BackupInterval <= 0 || a.options.BackupKeep < 1) {
+			return errors.New("backup interval and the number of backups to keep must be positive")
+		}
+		b := backups{
+			storage:  a.storage,
+			dir:      a.options.BackupDir,
+			interval: a.options.BackupInterval,
+			keep:     a.options.BackupKeep,
+		}
+		go b.Run(stop, signals)
+		if socket, ok := backupSocket(); ok {
+			l, err := store.ServeBackups(a.storage, socket)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = l.Close() }()
+		}
+
 		return s.Run()
 	}}
 }
@@ -79,3 +174,27 @@ func getEnv(key string, value string) string {
 	}
 	return v
 }
+
+func getEnvDuration(key string, value time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return value
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Wrong %s: %v", key, err)
+	}
+	return d
+}
+
+func getEnvInt(key string, value int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return value
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("Wrong %s: %v", key, err)
+	}
+	return i
+}
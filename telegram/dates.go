@@ -0,0 +1,200 @@
+package telegram
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var startInputLayouts = []string{
+	"2006-01-02 15:04",
+	"02.01.2006 15:04",
+	time.RFC3339,
+}
+
+// relativeDays are the days from today
+var relativeDays = map[string]int{
+	"today": 0, "сегодня": 0, "сёння": 0,
+	"tomorrow": 1, "завтра": 1, "заўтра": 1,
+	"послезавтра": 2, "паслязаўтра": 2,
+}
+
+// localWeekdays are the Russian and Belarusian names of the weekdays
+// in the nominative and the accusative, "в пятницу"
+var localWeekdays = map[string]time.Weekday{
+	"пн": time.Monday, "понедельник": time.Monday, "панядзелак": time.Monday,
+	"вт": time.Tuesday, "вторник": time.Tuesday, "аў": time.Tuesday, "аўторак": time.Tuesday,
+	"ср": time.Wednesday, "среда": time.Wednesday, "среду": time.Wednesday, "серада": time.Wednesday, "сераду": time.Wednesday,
+	"чт": time.Thursday, "четверг": time.Thursday, "чц": time.Thursday, "чацвер": time.Thursday,
+	"пт": time.Friday, "пятница": time.Friday, "пятницу": time.Friday, "пятніца": time.Friday, "пятніцу": time.Friday,
+	"сб": time.Saturday, "суббота": time.Saturday, "субботу": time.Saturday, "субота": time.Saturday, "суботу": time.Saturday,
+	"вс": time.Sunday, "воскресенье": time.Sunday, "нд": time.Sunday, "нядзеля": time.Sunday, "нядзелю": time.Sunday,
+}
+
+// nextWords skip the current week, "next fri"
+var nextWords = map[string]bool{
+	"next": true, "следующий": true, "следующую": true, "следующее": true,
+	"наступны": true, "наступную": true, "наступная": true,
+}
+
+// dateFillers are the prepositions which are skipped, "on fri at 7pm" or "в пятницу в 19:00"
+var dateFillers = map[string]bool{"on": true, "at": true, "в": true, "во": true, "у": true, "а": true}
+
+var (
+	clockPattern    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	dayMonthPattern = regexp.MustCompile(`^(\d{1,2})[./](\d{1,2})(?:[./](\d{2}|\d{4}))?$`)
+	isoDatePattern  = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+)
+
+var errWrongDate = errors.New("wrong date")
+
+// parseDate parses the start of the event like "tomorrow 19:30", "next fri 7pm",
+// "в пятницу 19:00" or "24.10 18:00" relative to now and in its location.
+// The date is ambiguous when the day or the year is guessed or the weekday
+// follows "next", it should be confirmed to the user.
+func parseDate(value string, now time.Time) (start time.Time, ambiguous bool, err error) {
+	location := now.Location()
+	for _, layout := range startInputLayouts {
+		if t, e := time.ParseInLocation(layout, value, location); e == nil {
+			return t, false, nil
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	var (
+		day           time.Time
+		hour, minute  = -1, 0
+		next          bool
+		weekday       bool
+		yearGuessed   bool
+		month, number int
+	)
+	setDay := func(d time.Time) error {
+		if d.IsZero() || !day.IsZero() {
+			return errWrongDate
+		}
+		day = d
+		return nil
+	}
+
+	tokens := strings.Fields(strings.ToLower(strings.Replace(value, ",", " ", -1)))
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		// "7 pm" is the same as "7pm"
+		if i+1 < len(tokens) && (tokens[i+1] == "am" || tokens[i+1] == "pm") {
+			token = token + tokens[i+1]
+			i++
+		}
+
+		wd, isWeekday := weekdays[token]
+		if !isWeekday {
+			wd, isWeekday = localWeekdays[token]
+		}
+		days, isRelative := relativeDays[token]
+
+		switch match := clockPattern.FindStringSubmatch(token); {
+		case dateFillers[token]:
+		case nextWords[token]:
+			next = true
+		case isRelative:
+			err = setDay(today.AddDate(0, 0, days))
+		case isWeekday:
+			offset := (int(wd) - int(today.Weekday()) + 7) % 7
+			if next && offset == 0 {
+				offset = 7
+			}
+			weekday = true
+			err = setDay(today.AddDate(0, 0, offset))
+		case match != nil && (match[2] != "" || match[3] != ""):
+			if hour >= 0 {
+				return start, false, errWrongDate
+			}
+			hour, minute, err = parseClock(match)
+		case dayMonthPattern.MatchString(token):
+			match = dayMonthPattern.FindStringSubmatch(token)
+			number, _ = strconv.Atoi(match[1])
+			month, _ = strconv.Atoi(match[2])
+			year := now.Year()
+			switch len(match[3]) {
+			case 0:
+				yearGuessed = true
+			case 2:
+				year, _ = strconv.Atoi(match[3])
+				year += 2000
+			default:
+				year, _ = strconv.Atoi(match[3])
+			}
+			err = setDay(date(year, month, number, location))
+		case isoDatePattern.MatchString(token):
+			match = isoDatePattern.FindStringSubmatch(token)
+			year, _ := strconv.Atoi(match[1])
+			month, _ = strconv.Atoi(match[2])
+			number, _ = strconv.Atoi(match[3])
+			err = setDay(date(year, month, number, location))
+		default:
+			err = errWrongDate
+		}
+		if err != nil {
+			return start, false, err
+		}
+	}
+	if hour < 0 || (next && !weekday) {
+		return start, false, errWrongDate
+	}
+
+	dayGuessed := day.IsZero()
+	if dayGuessed {
+		day = today
+	}
+	start = time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, location)
+	if start.Before(now) {
+		switch {
+		case dayGuessed:
+			start = start.AddDate(0, 0, 1)
+		case weekday:
+			start = start.AddDate(0, 0, 7)
+		case yearGuessed:
+			day = date(day.Year()+1, month, number, location)
+			if day.IsZero() {
+				return start, false, errWrongDate
+			}
+			start = time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, location)
+		}
+	}
+	return start, dayGuessed || yearGuessed || next, nil
+}
+
+// parseClock parses the matched time like 19:30, 7pm or 7:30am
+func parseClock(match []string) (hour int, minute int, err error) {
+	hour, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	switch match[3] {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, errWrongDate
+		}
+		hour = hour % 12
+		if match[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, errWrongDate
+	}
+	return hour, minute, nil
+}
+
+// date returns the midnight of the day, the zero time for the day
+// which does not exist like 31.02
+func date(year int, month int, day int, location *time.Location) time.Time {
+	d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+	if d.Day() != day || int(d.Month()) != month {
+		return time.Time{}
+	}
+	return d
+}
@@ -0,0 +1,61 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	minsk := time.FixedZone("Europe/Minsk", 3*60*60)
+	now := time.Date(2020, time.May, 17, 12, 0, 0, 0, minsk) // Sunday
+	at := func(month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(2020, month, day, hour, minute, 0, 0, minsk)
+	}
+
+	for _, tc := range []struct {
+		value     string
+		start     time.Time
+		ambiguous bool
+	}{
+		{"2020-05-20 19:30", at(time.May, 20, 19, 30), false},
+		{"20.05.2020 19:30", at(time.May, 20, 19, 30), false},
+		{"tomorrow 19:30", at(time.May, 18, 19, 30), false},
+		{"Tomorrow at 7 PM", at(time.May, 18, 19, 0), false},
+		{"завтра в 19:30", at(time.May, 18, 19, 30), false},
+		{"заўтра 7pm", at(time.May, 18, 19, 0), false},
+		{"послезавтра 12am", at(time.May, 19, 0, 0), false},
+		{"today 12pm", at(time.May, 17, 12, 0), false},
+		{"fri 7pm", at(time.May, 22, 19, 0), false},
+		{"on friday, 7:30pm", at(time.May, 22, 19, 30), false},
+		{"next fri 7pm", at(time.May, 22, 19, 0), true},
+		{"sun 18:00", at(time.May, 17, 18, 0), false},
+		{"sun 10:00", at(time.May, 24, 10, 0), false},
+		{"next sun 18:00", at(time.May, 24, 18, 0), true},
+		{"в пятницу 19:00", at(time.May, 22, 19, 0), false},
+		{"в следующую среду в 19:00", at(time.May, 20, 19, 0), true},
+		{"у пятніцу 19:00", at(time.May, 22, 19, 0), false},
+		{"24.10 18:00", at(time.October, 24, 18, 0), true},
+		{"18:00 24/10/20", at(time.October, 24, 18, 0), false},
+		{"01.05 18:00", time.Date(2021, time.May, 1, 18, 0, 0, 0, minsk), true},
+		{"19:30", at(time.May, 17, 19, 30), true},
+		{"11:00", at(time.May, 18, 11, 0), true},
+	} {
+		start, ambiguous, err := parseDate(tc.value, now)
+		if err != nil {
+			t.Errorf("%q: %v", tc.value, err)
+			continue
+		}
+		if !start.Equal(tc.start) || ambiguous != tc.ambiguous {
+			t.Errorf("%q: %v, ambiguous %v, want %v, %v", tc.value, start, ambiguous, tc.start, tc.ambiguous)
+		}
+	}
+
+	for _, value := range []string{
+		"someday", "tomorrow", "19", "31.02 10:00", "29.02 10:00", "25:00 tomorrow", "13pm",
+		"next 19:00", "tomorrow fri 19:00", "19:00 20:00",
+	} {
+		if start, _, err := parseDate(value, now); err == nil {
+			t.Errorf("%q is parsed as %v", value, start)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package telegram
+
+import (
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// fakeSender records everything the handler sends to Telegram
+type fakeSender struct {
+	mu             sync.Mutex
+	messages       []tgbotapi.Chattable
+	answers        []tgbotapi.CallbackConfig
+	deleted        []int
+	pinned         []int
+	administrators []tgbotapi.ChatMember
+	keyboards      []sentKeyboard
+	lastMessageId  int
+}
+
+type sentKeyboard struct {
+	messageId int
+	markup    tgbotapi.InlineKeyboardMarkup
+}
+
+func (f *fakeSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.messages = append(f.messages, c)
+
+	var chatId int64
+	switch m := c.(type) {
+	case tgbotapi.MessageConfig:
+		chatId = m.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		if m.ReplyMarkup != nil {
+			f.keyboards = append(f.keyboards, sentKeyboard{m.MessageID, *m.ReplyMarkup})
+		}
+		return tgbotapi.Message{MessageID: m.MessageID, Chat: &tgbotapi.Chat{ID: m.ChatID}}, nil
+	case tgbotapi.DocumentConfig:
+		chatId = m.ChatID
+	}
+	f.lastMessageId++
+	if m, ok := c.(tgbotapi.MessageConfig); ok {
+		if markup, ok := m.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup); ok {
+			f.keyboards = append(f.keyboards, sentKeyboard{f.lastMessageId, markup})
+		}
+	}
+	return tgbotapi.Message{MessageID: f.lastMessageId, Chat: &tgbotapi.Chat{ID: chatId}}, nil
+}
+
+// button returns the data of the button with the text on the last message having it
+func (f *fakeSender) button(text string) (data string, messageId int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.keyboards) - 1; i >= 0; i-- {
+		for _, row := range f.keyboards[i].markup.InlineKeyboard {
+			for _, b := range row {
+				if b.Text == text && b.CallbackData != nil {
+					return *b.CallbackData, f.keyboards[i].messageId
+				}
+			}
+		}
+	}
+	return "", 0
+}
+
+func (f *fakeSender) AnswerCallbackQuery(config tgbotapi.CallbackConfig) (tgbotapi.APIResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.answers = append(f.answers, config)
+	return tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (f *fakeSender) DeleteMessage(config tgbotapi.DeleteMessageConfig) (tgbotapi.APIResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, config.MessageID)
+	return tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (f *fakeSender) PinChatMessage(config tgbotapi.PinChatMessageConfig) (tgbotapi.APIResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pinned = append(f.pinned, config.MessageID)
+	return tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (f *fakeSender) UnpinChatMessage(config tgbotapi.UnpinChatMessageConfig) (tgbotapi.APIResponse, error) {
+	return tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (f *fakeSender) GetChatAdministrators(config tgbotapi.ChatConfig) ([]tgbotapi.ChatMember, error) {
+	return f.administrators, nil
+}
+
+// documents returns the sent documents
+func (f *fakeSender) documents() (docs []tgbotapi.DocumentConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.messages {
+		if d, ok := c.(tgbotapi.DocumentConfig); ok {
+			docs = append(docs, d)
+		}
+	}
+	return docs
+}
+
+// texts returns the texts of the sent and edited messages starting from the index
+func (f *fakeSender) texts(from int) (texts []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.messages[from:] {
+		switch m := c.(type) {
+		case tgbotapi.MessageConfig:
+			texts = append(texts, m.Text)
+		case tgbotapi.EditMessageTextConfig:
+			texts = append(texts, m.Text)
+		}
+	}
+	return texts
+}
+
+func (f *fakeSender) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.messages)
+}
+
+// fakeSource delivers the prepared updates and closes the channel
+type fakeSource struct {
+	updates []tgbotapi.Update
+}
+
+func (f *fakeSource) Updates() (tgbotapi.UpdatesChannel, <-chan error, error) {
+	ch := make(chan tgbotapi.Update, len(f.updates))
+	for _, u := range f.updates {
+		ch <- u
+	}
+	close(ch)
+	return ch, nil, nil
+}
+
+const testChatId = 100
+
+var testUsers = map[string]*tgbotapi.User{
+	"smith": {ID: 1, UserName: "smith", FirstName: "John", LastName: "Smith"},
+	"ann":   {ID: 2, UserName: "ann", FirstName: "Ann"},
+	"bob":   {ID: 3, FirstName: "Bob"},
+}
+
+// command makes the update of the command like "smith: /add @ann"
+func command(line string) tgbotapi.Update {
+	parts := strings.SplitN(line, ": ", 2)
+	text := parts[1]
+	length := len(strings.Fields(text)[0])
+	entities := []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		MessageID: 1,
+		From:      testUsers[parts[0]],
+		Chat:      &tgbotapi.Chat{ID: testChatId, Type: "group"},
+		Text:      text,
+		Entities:  &entities,
+	}}
+}
+
+// press makes the update of the button pressed on the list message
+func press(user string, data string, messageId int) tgbotapi.Update {
+	return tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "query",
+		From:    testUsers[user],
+		Message: &tgbotapi.Message{MessageID: messageId, Chat: &tgbotapi.Chat{ID: testChatId, Type: "group"}},
+		Data:    data,
+	}}
+}
@@ -1,26 +1,68 @@
 package telegram
 
 import (
+	"bytes"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/pkg/errors"
+	"github.com/taras-by/tbot/i18n"
 	"github.com/taras-by/tbot/store"
 	"log"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
 const (
 	maxLengthStringArgument = 50
+	maxLengthDescription    = 300
 	maxParticipants         = 100
+	maxMessageLength        = 4096
+	mentionsPerMessage      = 5
+	maxCallbackTextLength   = 200
+	confirmationLifetime    = 5 * time.Second
+	resetQuestionLifetime   = time.Minute
+	undoWindow              = 10 * time.Minute
+	defaultResetAfter       = 2 * time.Hour
+	maxReminders            = 5
+	defaultHistoryLength    = 5
+	maxHistoryLength        = 20
 )
 
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// personalCommands act on the sender as well, only acting on others can be admin-only
+var personalCommands = map[string]bool{"add": true, "rm": true}
+
+// unrestricted commands can't be made admin-only
+var unrestricted = map[string]bool{"admin": true, "help": true, "settings": true, "start": true}
+
 type MessageHandler struct {
-	Bot     *tgbotapi.BotAPI
-	Storage *store.Storage
-	routes  []route
-	Version string
+	Bot     Sender
+	Storage store.Repository
+	// Owners are the Telegram user ids allowed to use the admin-only commands in any chat
+	Owners []int
+	// Now is the clock of the bot, time.Now if nil
+	Now       func() time.Time
+	routes    []route
+	callbacks map[string]func(c conversation)
+	Version   string
+	// members are the saved chat members by the chat and the user,
+	// the unchanged ones are not saved on every message
+	members   map[string]store.Member
+	membersMu sync.Mutex
 }
 
 type route struct {
@@ -30,10 +72,16 @@ type route struct {
 }
 
 type conversation struct {
-	chatId  int64
-	args    string
-	checker *regexp.Regexp
-	message *tgbotapi.Message
+	chatId   int64
+	event    store.Event
+	args     string
+	checker  *regexp.Regexp
+	message  *tgbotapi.Message
+	user     *tgbotapi.User
+	callback *tgbotapi.CallbackQuery
+	printer  i18n.Printer
+	// settings are loaded once for the conversation
+	settings store.Settings
 }
 
 func (h *MessageHandler) handle(message *tgbotapi.Message) {
@@ -43,6 +91,8 @@ func (h *MessageHandler) handle(message *tgbotapi.Message) {
 
 	log.Printf("Message: [%s] %s", message.From.UserName, message.Text)
 
+	h.rememberMembers(message)
+
 	if message.IsCommand() == false { // ignore any non-command Updates
 		return
 	}
@@ -50,9 +100,19 @@ func (h *MessageHandler) handle(message *tgbotapi.Message) {
 	args := strings.TrimSpace(message.CommandArguments())
 	cmd := message.Command()
 	chatId := message.Chat.ID
+	settings, err := h.Storage.FindSettings(chatId)
+	if err != nil {
+		h.sendMessageToChat(chatId, errorText(i18n.New(i18n.Default), err))
+		return
+	}
+	p := h.printer(settings, message.From)
 
-	if len([]rune(args)) > maxLengthStringArgument {
-		h.sendMessageToChat(chatId, store.Escape("Parameter too long"))
+	maxLength := maxLengthStringArgument
+	if cmd == "about" {
+		maxLength = maxLengthDescription
+	}
+	if len([]rune(args)) > maxLength {
+		h.sendMessageToChat(chatId, store.Escape(p.T("Parameter too long")))
 		return
 	}
 
@@ -74,11 +134,26 @@ func (h *MessageHandler) handle(message *tgbotapi.Message) {
 			commandIsOk = true
 			log.Printf("command: \"%v\", arguments: \"%v\"", cmd, args)
 
+			event, err := h.Storage.ActiveEvent(chatId)
+			if err != nil {
+				h.sendMessageToChat(chatId, errorText(p, err))
+				return
+			}
+
 			c := conversation{
-				chatId:  chatId,
-				args:    args,
-				checker: checker,
-				message: message,
+				chatId:   chatId,
+				event:    event,
+				args:     args,
+				checker:  checker,
+				message:  message,
+				user:     message.From,
+				printer:  p,
+				settings: settings,
+			}
+
+			denial := p.T("Only administrators can use /%s", cmd)
+			if !personalCommands[cmd] && !h.allowed(c, cmd, denial) {
+				return
 			}
 
 			route.command(c)
@@ -86,247 +161,1727 @@ func (h *MessageHandler) handle(message *tgbotapi.Message) {
 		}
 	}
 	if commandIsOk == false {
-		h.sendMessageToChat(chatId, store.Escape("Wrong command"))
+		h.sendMessageToChat(chatId, store.Escape(p.T("Wrong command")))
 		return
 	}
 }
 
-func (h *MessageHandler) list(c conversation) {
-	text := h.participantsText(c.chatId)
-	h.sendMessageToChat(c.chatId, text)
+// handleCallback handles the buttons of the list message and the questions.
+// The data of a button is the action, the event id and the optional
+// arguments, e.g. "join:2" or "reset:2:1589733000"
+func (h *MessageHandler) handleCallback(query *tgbotapi.CallbackQuery) {
+	if query.Message == nil { // ignore buttons of inline messages
+		return
+	}
+
+	log.Printf("Callback: [%s] %s", query.From.UserName, query.Data)
+
+	chatId := query.Message.Chat.ID
+	h.rememberMember(chatId, *query.From)
+	settings, err := h.Storage.FindSettings(chatId)
+	if err != nil {
+		h.answerCallback(query, plain(errorText(i18n.New(i18n.Default), err)))
+		return
+	}
+	p := h.printer(settings, query.From)
+
+	data := strings.SplitN(query.Data, ":", 3)
+	command, ok := h.callbacks[data[0]]
+	if !ok || len(data) < 2 {
+		h.answerCallback(query, p.T("Wrong button"))
+		return
+	}
+
+	eventId, err := strconv.Atoi(data[1])
+	if err != nil {
+		h.answerCallback(query, p.T("Wrong button"))
+		return
+	}
+
+	event, err := h.Storage.FindEvent(chatId, eventId)
+	if err != nil {
+		h.answerCallback(query, plain(errorText(p, err)))
+		return
+	}
+
+	c := conversation{
+		chatId:   chatId,
+		event:    event,
+		message:  query.Message,
+		user:     query.From,
+		callback: query,
+		printer:  p,
+		settings: settings,
+	}
+	if len(data) == 3 {
+		c.args = data[2]
+	}
+	command(c)
 }
 
-func (h *MessageHandler) addMe(c conversation) {
+func (h *MessageHandler) list(c conversation) {
+	h.postList(c.printer, c.settings, c.event)
+}
 
-	creationTime := time.Now()
-	existingParticipant, err := h.Storage.FindByLink("@"+c.message.From.UserName, c.chatId)
-	if err == nil {
-		if existingParticipant.IsUnresolved() == true {
-			creationTime = existingParticipant.Time
-			h.Storage.Delete(existingParticipant)
-			//sendMessageToChat(chatId, "Update unresolved")
-		} else {
-			h.sendMessageToChat(c.chatId, "You are already a participant")
-			return
-		}
+func (h *MessageHandler) pin(c conversation) {
+	c.event.Pinned = true
+	if err := h.Storage.SaveEvent(c.event); err != nil {
+		h.replyError(c, err)
+		return
 	}
+	h.postList(c.printer, c.settings, c.event)
+}
 
-	if h.Storage.CountByChatId(c.chatId) >= maxParticipants {
-		h.sendMessageToChat(c.chatId, fmt.Sprintf("Maximum chat participants: *%v*", maxParticipants))
+func (h *MessageHandler) unpin(c conversation) {
+	c.event.Pinned = false
+	if err := h.Storage.SaveEvent(c.event); err != nil {
+		h.replyError(c, err)
 		return
 	}
+	if c.event.MessageId != 0 {
+		if _, err := h.Bot.UnpinChatMessage(tgbotapi.UnpinChatMessageConfig{ChatID: c.chatId}); err != nil {
+			log.Print(err)
+		}
+	}
+	h.reply(c, c.printer.T("*Unpinned*"))
+}
 
-	participant := h.Storage.Create(
-		store.Participant{
-			User: store.User{
-				Id:        strconv.Itoa(c.message.From.ID),
-				UserName:  c.message.From.UserName,
-				FirstName: c.message.From.FirstName,
-				LastName:  c.message.From.LastName,
-				Type:      store.UserTelegram,
-			},
-			Time:   creationTime,
-			ChatId: c.chatId,
-		},
-	)
+func (h *MessageHandler) addMe(c conversation) {
+	h.answer(c, store.StatusGoing)
+}
 
-	text := fmt.Sprintf("*Added* %s", store.Escape(participant.Link())) + "\n" +
-		h.participantsText(c.chatId)
-	h.sendMessageToChat(c.chatId, text)
+func (h *MessageHandler) maybe(c conversation) {
+	h.answer(c, store.StatusMaybe)
+}
+
+func (h *MessageHandler) decline(c conversation) {
+	h.answer(c, store.StatusDeclined)
+}
+
+func (h *MessageHandler) answer(c conversation, status store.Status) {
+	h.add(c, store.Participant{
+		User:    telegramUser(c.user),
+		Time:    h.now(),
+		ChatId:  c.chatId,
+		EventId: c.event.Id,
+		Status:  status,
+	})
 }
 
 func (h *MessageHandler) addByLink(c conversation) {
 
 	match := c.checker.FindStringSubmatch(c.args)
 	if len(match) != 2 {
-		h.sendMessageToChat(c.chatId, "Error")
+		h.reply(c, c.printer.T("Error"))
+		return
+	}
+
+	h.add(c, store.Participant{
+		User: store.User{
+			UserName: match[1],
+			Type:     store.UserUnresolved,
+		},
+		Time:    h.now(),
+		ChatId:  c.chatId,
+		EventId: c.event.Id,
+	})
+}
+
+// addByName adds the guest of the sender like "/add My brother John", the same as "/add +1 My brother John"
+func (h *MessageHandler) addByName(c conversation) {
+	h.bringGuests(c, 1, c.args)
+}
+
+// add puts the participant to the list unless the same person is already there
+func (h *MessageHandler) add(c conversation, participant store.Participant) {
+	self := participant.User.Type == store.UserTelegram
+	if !isSender(c, participant) && !h.allowed(c, "add", c.printer.T("Only administrators can add other participants")) {
 		return
 	}
 
-	userName := match[1]
-	existingParticipant, err := h.Storage.FindByLink("@"+userName, c.chatId)
-	if err == nil && existingParticipant.Id() != "" {
-		h.sendMessageToChat(c.chatId, "User is already in the list of participants")
+	before, err := h.Storage.FindByEvent(c.event)
+	if err != nil {
+		h.replyError(c, err)
 		return
 	}
 
-	if h.Storage.CountByChatId(c.chatId) >= maxParticipants {
-		h.sendMessageToChat(c.chatId, fmt.Sprintf("Maximum chat participants: *%v*", maxParticipants))
+	participant, result, err := h.Storage.AddIfAbsent(participant, capacity(c.settings, c.event))
+	if err != nil {
+		h.replyError(c, err)
 		return
 	}
 
-	participant := h.Storage.Create(
-		store.Participant{
-			User: store.User{
-				UserName: userName,
-				Type:     store.UserUnresolved,
-			},
-			Time:   time.Now(),
-			ChatId: c.chatId,
-		},
-	)
+	if result == store.Duplicate {
+		if self {
+			h.reply(c, c.printer.T("You have already answered: *%s*", c.printer.T(string(participant.State()))))
+		} else {
+			h.reply(c, c.printer.T("User is already in the list of participants"))
+		}
+		return
+	}
 
-	text := fmt.Sprintf("*Added* %s", store.Escape(participant.Link())) + "\n" +
-		h.participantsText(c.chatId)
-	h.sendMessageToChat(c.chatId, text)
+	h.added(c, before, participant, result)
 }
 
-func (h *MessageHandler) addByName(c conversation) {
+// addGuests adds the guests brought by the sender like "/add +2" or "/add +1 John"
+func (h *MessageHandler) addGuests(c conversation) {
+	match := c.checker.FindStringSubmatch(c.args)
+	if len(match) != 3 {
+		h.reply(c, c.printer.T("Error"))
+		return
+	}
+	count, err := strconv.Atoi(match[1])
+	if err != nil || count < 1 || count > maxParticipants {
+		h.reply(c, c.printer.T("Wrong parameter"))
+		return
+	}
+	name := strings.TrimSpace(match[2])
+	if name != "" && count > 1 {
+		h.reply(c, c.printer.T("Add the named guests one by one: /add +1 John"))
+		return
+	}
+	h.bringGuests(c, count, name)
+}
 
-	existingParticipant, err := h.Storage.FindByName(c.args, c.chatId)
-	if err == nil && existingParticipant.Id() != "" {
-		h.sendMessageToChat(c.chatId, "User is already in the list of participants")
+// bringGuests adds the guests owned by the sender, the unnamed ones are +1, +2 and so on
+func (h *MessageHandler) bringGuests(c conversation, count int, name string) {
+	settings := c.settings
+	if settings.NoGuests {
+		h.reply(c, c.printer.T("Guests are not allowed in this chat"))
 		return
 	}
 
-	if h.Storage.CountByChatId(c.chatId) >= maxParticipants {
-		h.sendMessageToChat(c.chatId, fmt.Sprintf("Maximum chat participants: *%v*", maxParticipants))
+	owner := telegramUser(c.user)
+	participants, err := h.Storage.FindByEvent(c.event)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	names := map[string]bool{}
+	for _, p := range participants {
+		if p.IsGuestOf(owner) {
+			names[p.Name()] = true
+		}
+	}
+	if quota := settings.GuestQuota; quota > 0 && len(names)+count > quota {
+		h.reply(c, c.printer.N("You can bring %d guests at most", quota, quota))
 		return
 	}
 
-	participant := h.Storage.Create(
-		store.Participant{
-			User: store.User{
-				UserName: c.args,
-				Type:     store.UserGuest,
-			},
-			Time:   time.Now(),
-			ChatId: c.chatId,
-		},
-	)
+	var texts []string
+	for i := 0; i < count; i++ {
+		guestName := name
+		for n := 1; guestName == ""; n++ {
+			if !names["+"+strconv.Itoa(n)] {
+				guestName = "+" + strconv.Itoa(n)
+			}
+		}
+		names[guestName] = true
 
-	text := fmt.Sprintf("*Added* %s", store.Escape(participant.Link())) + "\n" +
-		h.participantsText(c.chatId)
-	h.sendMessageToChat(c.chatId, text)
+		participant, result, err := h.Storage.AddIfAbsent(store.Participant{
+			User:    store.NewGuest(owner, guestName),
+			Owner:   &owner,
+			Time:    h.now(),
+			ChatId:  c.chatId,
+			EventId: c.event.Id,
+		}, capacity(c.settings, c.event))
+		if err != nil {
+			if len(texts) == 0 {
+				h.replyError(c, err)
+				return
+			}
+			// the guests added before the failure stay in the list
+			texts = append(texts, errorText(c.printer, err))
+			break
+		}
+		link := store.Escape(withOwner(c.printer, participant, participant.Link()))
+		switch result {
+		case store.Duplicate:
+			texts = append(texts, c.printer.T("%s is already in the list of participants", link))
+		case store.Waitlisted:
+			texts = append(texts, c.printer.T("*Waitlisted* %s", link))
+		default:
+			texts = append(texts, c.printer.T("*Added* %s", link))
+		}
+	}
+	if len(texts) > 0 {
+		h.replyWithList(c, strings.Join(texts, "\n"))
+	}
 }
 
 func (h *MessageHandler) addByNumber(c conversation) {
-	h.sendMessageToChat(c.chatId, "Fail. UserName as an number")
+	h.reply(c, c.printer.T("Fail. UserName as an number"))
 }
 
 func (h *MessageHandler) removeMe(c conversation) {
 
+	participant, err := h.findMe(c)
+	if errors.Is(err, store.ErrNotFound) {
+		h.reply(c, c.printer.T("You are not a participant yet"))
+		return
+	}
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	h.remove(c, participant)
+}
+
+// findMe returns the participant who sent the command,
+// including the unresolved one added by link
+func (h *MessageHandler) findMe(c conversation) (store.Participant, error) {
 	participant, err := h.Storage.Find(store.Participant{
-		User: store.User{
-			Id:        strconv.Itoa(c.message.From.ID),
-			UserName:  c.message.From.UserName,
-			FirstName: c.message.From.FirstName,
-			LastName:  c.message.From.LastName,
-			Type:      store.UserTelegram,
-		},
-		ChatId: c.chatId,
+		User:    telegramUser(c.user),
+		ChatId:  c.chatId,
+		EventId: c.event.Id,
 	})
+	if errors.Is(err, store.ErrNotFound) && c.user.UserName != "" {
+		participant, err = h.Storage.FindByLink("@"+c.user.UserName, c.event)
+	}
+	return participant, err
+}
+
+// removeByNumber removes the participant by the number in the list like "/rm 3",
+// the waitlist has its own numbers like "/rm w1"
+func (h *MessageHandler) removeByNumber(c conversation) {
+	participants, err := h.Storage.FindByEvent(c.event)
 	if err != nil {
-		participant, err = h.Storage.FindByLink("@"+c.message.From.UserName, c.chatId)
-		if err != nil {
-			h.sendMessageToChat(c.chatId, "You are not a participant yet")
+		h.replyError(c, err)
+		return
+	}
+
+	number := strings.ToLower(c.args)
+	for i, n := range listNumbers(participants, capacity(c.settings, c.event)) {
+		if n == number {
+			h.remove(c, participants[i])
 			return
 		}
 	}
+	h.replyError(c, store.NewError(store.ErrNotFound, "Participant with number %s not found", c.args))
+}
+
+func (h *MessageHandler) removeByLink(c conversation) {
 
-	h.Storage.Delete(participant)
+	linkString := string(c.checker.Find([]byte(c.args)))
+	participant, err := h.Storage.FindByLink(linkString, c.event)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
 
-	text := fmt.Sprintf("*Removed* %s", store.Escape(participant.Link())) + "\n" +
-		h.participantsText(c.chatId)
-	h.sendMessageToChat(c.chatId, text)
+	h.remove(c, participant)
 }
 
-func (h *MessageHandler) removeByNumber(c conversation) {
+func (h *MessageHandler) removeByName(c conversation) {
 
-	numberString := string(c.checker.Find([]byte(c.args)))
-	number, err := strconv.Atoi(numberString)
+	participants, err := h.Storage.FindByEvent(c.event)
 	if err != nil {
-		h.sendMessageToChat(c.chatId, "Wrong parameter")
+		h.replyError(c, err)
 		return
 	}
+	// the own guest goes first, "/rm +1" removes the guest of the sender
+	for _, p := range participants {
+		if p.Name() == c.args && p.IsGuestOf(telegramUser(c.user)) {
+			h.remove(c, p)
+			return
+		}
+	}
 
-	participant, err := h.Storage.FindByNumber(number, c.chatId)
+	participant, err := h.Storage.FindByName(c.args, c.event)
 	if err != nil {
-		h.sendMessageToChat(c.chatId, store.Escape(err.Error()))
+		h.replyError(c, err)
 		return
 	}
 
-	h.Storage.Delete(participant)
+	h.remove(c, participant)
+}
 
-	text := fmt.Sprintf("*Removed* %s", store.Escape(participant.Link())) + "\n" +
-		h.participantsText(c.chatId)
-	h.sendMessageToChat(c.chatId, text)
+func (h *MessageHandler) added(c conversation, before []store.Participant, participant store.Participant, result store.AddResult) {
+	after, err := h.Storage.FindByEvent(c.event)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	limit := capacity(c.settings, c.event)
+	link := store.Escape(withOwner(c.printer, participant, participant.Link()))
+
+	var text string
+	switch participant.State() {
+	case store.StatusMaybe:
+		text = c.printer.T("*Maybe* %s", link)
+	case store.StatusDeclined:
+		text = c.printer.T("*Declined* %s", link)
+	default:
+		text = c.printer.T("*Added* %s", link)
+		if result == store.Waitlisted {
+			text = c.printer.T("*Waitlisted* %s", link)
+		}
+	}
+	promoted := promotedText(c.printer, before, after, limit)
+	h.announcePromoted(c, promoted)
+	h.replyWithList(c, text)
 }
 
-func (h *MessageHandler) removeByLink(c conversation) {
+// remove deletes the participant together with the guests the participant brought
+func (h *MessageHandler) remove(c conversation, participant store.Participant) {
+	if !h.mayRemove(c, participant) {
+		return
+	}
 
-	linkString := string(c.checker.Find([]byte(c.args)))
-	participant, err := h.Storage.FindByLink(linkString, c.chatId)
+	before, err := h.Storage.FindByEvent(c.event)
 	if err != nil {
-		h.sendMessageToChat(c.chatId, store.Escape(err.Error()))
+		h.replyError(c, err)
 		return
 	}
 
-	h.Storage.Delete(participant)
+	removed := []store.Participant{participant}
+	if participant.User.Type != store.UserGuest {
+		for _, p := range before {
+			if p.IsGuestOf(participant.User) {
+				removed = append(removed, p)
+			}
+		}
+	}
+	var links []string
+	for _, p := range removed {
+		if err = h.Storage.Delete(p); err != nil {
+			h.replyError(c, err)
+			return
+		}
+		links = append(links, withOwner(c.printer, p, p.Link()))
+	}
+	h.journal(c, store.Operation{Kind: store.OperationRemove, Participants: removed})
+
+	after, err := h.Storage.FindByEvent(c.event)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	promoted := promotedText(c.printer, before, after, capacity(c.settings, c.event))
+	h.announcePromoted(c, promoted)
+	h.replyWithList(c, c.printer.T("*Removed* %s", store.Escape(strings.Join(links, ", "))))
+}
 
-	text := fmt.Sprintf("*Removed* %s", store.Escape(participant.Link())) + "\n" +
-		h.participantsText(c.chatId)
-	h.sendMessageToChat(c.chatId, text)
+// mayRemove tells if the sender can remove the participant. Anyone can remove
+// themselves and their guests, the guests of others only the administrators.
+func (h *MessageHandler) mayRemove(c conversation, participant store.Participant) bool {
+	switch {
+	case isSender(c, participant), participant.IsGuestOf(telegramUser(c.user)):
+		return true
+	case participant.Owner != nil:
+		return h.requireAdmin(c, c.printer.T("Only the one who brought the guest or an administrator can remove it"))
+	}
+	return h.allowed(c, "rm", c.printer.T("Only administrators can remove other participants"))
 }
 
-func (h *MessageHandler) removeByName(c conversation) {
+// announcePromoted mentions the promoted participants in the chat
+func (h *MessageHandler) announcePromoted(c conversation, text string) {
+	if text != "" {
+		h.sendMessageToChat(c.chatId, text)
+	}
+}
 
-	participant, err := h.Storage.FindByName(c.args, c.chatId)
-	if err != nil {
-		h.sendMessageToChat(c.chatId, store.Escape(err.Error()))
+func (h *MessageHandler) setCapacity(c conversation) {
+	limit := 0
+	if value := clearable(c.args); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxParticipants {
+			h.reply(c, c.printer.T("Capacity must be from 1 to *%v*", maxParticipants))
+			return
+		}
+	}
+	c.event.Capacity = limit
+	h.saveEvent(c, "Capacity")
+}
+
+func (h *MessageHandler) setRecurrence(c conversation) {
+	if clearable(c.args) == "" {
+		c.event.Recurrence = nil
+		h.saveEvent(c, "Schedule")
+		return
+	}
+
+	match := c.checker.FindStringSubmatch(c.args)
+	if len(match) != 5 {
+		h.reply(c, c.printer.T("Error"))
+		return
+	}
+
+	weekday, ok := weekdays[strings.ToLower(match[1])]
+	hour, _ := strconv.Atoi(match[2])
+	minute, _ := strconv.Atoi(match[3])
+	resetAfter := defaultResetAfter
+	if match[4] != "" {
+		var err error
+		resetAfter, err = time.ParseDuration(match[4])
+		if err != nil || resetAfter <= 0 {
+			ok = false
+		}
+	}
+	if !ok || hour > 23 || minute > 59 {
+		h.reply(c, store.Escape(c.printer.T("Wrong schedule, use format like: tue 19:00 2h")))
 		return
 	}
 
-	h.Storage.Delete(participant)
+	recurrence := &store.Recurrence{Weekday: weekday, Hour: hour, Minute: minute, ResetAfter: resetAfter}
+	c.event.Recurrence = recurrence
+	// keep the current start while the event is still going on
+	c.event.Start = recurrence.Next(h.now().In(c.printer.Location()).Add(-resetAfter))
+	h.saveEvent(c, "Schedule")
+}
 
-	text := fmt.Sprintf("*Removed* %s", store.Escape(participant.Link())) + "\n" +
-		h.participantsText(c.chatId)
-	h.sendMessageToChat(c.chatId, text)
+// restartEvent archives the finished list of the recurring event and opens the next one
+func (h *MessageHandler) restartEvent(event store.Event, now time.Time) {
+	settings := h.settings(event.ChatId)
+	p := h.printer(settings, nil)
+	start := event.Recurrence.Next(now.In(p.Location()))
+	if _, err := h.Storage.ArchiveEvent(event, start); err != nil {
+		log.Print(err)
+		return
+	}
+	// the event could change since the scheduler read it
+	event, err := h.Storage.FindEvent(event.ChatId, event.Id)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	h.sendMessageToChat(event.ChatId, p.T("*%s* is open for %s",
+		store.Escape(eventName(p, event)), p.Date(event.Start)))
+	h.postList(p, settings, event)
 }
 
+// reset asks to confirm the reset, the question is deleted after resetQuestionLifetime
 func (h *MessageHandler) reset(c conversation) {
-	err := h.Storage.DeleteAll(c.chatId)
+	data := fmt.Sprintf("%d:%d", c.event.Id, h.now().Add(resetQuestionLifetime).Unix())
+	msg := tgbotapi.NewMessage(c.chatId, c.printer.T("Are you sure? All participants of *%s* will be removed",
+		store.Escape(eventName(c.printer, c.event))))
+	msg.ParseMode = "markdown"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(c.printer.T("Yes"), "reset:"+data),
+		tgbotapi.NewInlineKeyboardButtonData(c.printer.T("No"), "cancel:"+data),
+	))
+	sent, err := h.Bot.Send(msg)
 	if err != nil {
-		h.sendMessageToChat(c.chatId, store.Escape(err.Error()))
+		log.Print(err)
 		return
 	}
+	time.AfterFunc(resetQuestionLifetime, func() {
+		h.deleteMessage(c.chatId, sent.MessageID)
+	})
+}
 
-	h.sendMessageToChat(c.chatId, "All participants was deleted")
+// answerReset tells if the sender can answer the reset question which has not
+// expired yet, the expired question is deleted
+func (h *MessageHandler) answerReset(c conversation) bool {
+	expires, err := strconv.ParseInt(c.args, 10, 64)
+	if err != nil || h.now().Unix() > expires {
+		h.answerCallback(c.callback, c.printer.T("The question has expired, send /reset again"))
+		h.deleteMessage(c.chatId, c.callback.Message.MessageID)
+		return false
+	}
+	return h.allowed(c, "reset", c.printer.T("Only administrators can use /%s", "reset"))
 }
 
-func (h *MessageHandler) help(c conversation) {
-	text := "*Help:*\n" +
-		"/list - participants list\n" +
-		"/add - add yourself or someone\n" +
-		"/rm - remove yourself or someone\n" +
-		"/reset - remove all\n" +
-		//"/ping - turn to non-participants\n" +
-		"/help - help\n" +
-		"\n" +
-		"*Examples:*\n" +
-		"``` /add @smith\n" +
-		" /add My brother John\n" +
-		" /rm @smith\n" +
-		" /rm My brother John\n" +
-		" /rm 3\n" +
-		"```\n" +
-		"The last example is the removal of the third participant\n\n" +
-		"_Version: " + h.Version + "_"
-	h.sendMessageToChat(c.chatId, text)
+// resetConfirmed archives the list when Yes is pressed before the question expires
+func (h *MessageHandler) resetConfirmed(c conversation) {
+	if !h.answerReset(c) {
+		return
+	}
+	h.deleteMessage(c.chatId, c.callback.Message.MessageID)
+
+	archive, err := h.Storage.ArchiveEvent(c.event, time.Time{})
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	if archive.Id != 0 {
+		h.journal(c, store.Operation{Kind: store.OperationReset, ArchiveId: archive.Id})
+	}
+
+	h.answerCallback(c.callback, c.printer.T("Reset"))
+	c.callback = nil
+	h.replyWithList(c, c.printer.T("All participants was deleted, /undo restores them"))
 }
 
-func (h *MessageHandler) ping(c conversation) {
-	h.sendMessageToChat(c.chatId, "Turn to non-participants... *Not implemented*.\nWelcome to https://github.com/taras-by/tbot")
+// resetCancelled deletes the question when No is pressed by the one who can reset the list
+func (h *MessageHandler) resetCancelled(c conversation) {
+	if !h.answerReset(c) {
+		return
+	}
+	h.deleteMessage(c.chatId, c.callback.Message.MessageID)
+	h.answerCallback(c.callback, c.printer.T("Cancelled"))
 }
 
-func (h *MessageHandler) participantsText(chatId int64) (text string) {
-	participants := h.Storage.FindByChatId(chatId)
-	if len(participants) == 0 {
-		return "No participants"
+// journal saves the destructive change for /undo
+func (h *MessageHandler) journal(c conversation, op store.Operation) {
+	op.ChatId = c.chatId
+	op.EventId = c.event.Id
+	op.User = telegramUser(c.user)
+	op.Time = h.now()
+	if _, err := h.Storage.PushOperation(op); err != nil {
+		log.Print(err)
 	}
-	text = "Participants:\n"
-	for i, p := range participants {
-		text = text + fmt.Sprintf(" *%v)* %v\n", i+1, store.Escape(p.Name()))
+}
+
+// undo restores the last reset or removal in the chat made within undoWindow.
+// Only the one who made the change or an administrator can undo it.
+func (h *MessageHandler) undo(c conversation) {
+	op, err := h.Storage.LastOperation(c.chatId)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	if h.now().Sub(op.Time) > undoWindow {
+		h.reply(c, c.printer.T("Nothing to undo"))
+		return
+	}
+	if op.User.Id != strconv.Itoa(c.user.ID) && !h.requireAdmin(c, c.printer.T("Only administrators can undo the changes of others")) {
+		return
+	}
+
+	if c.event, err = h.Storage.FindEvent(c.chatId, op.EventId); err != nil {
+		h.replyError(c, err)
+		return
+	}
+	// the operation is deleted first, so the concurrent undo fails
+	if err = h.Storage.DeleteOperation(op); err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	var text string
+	switch op.Kind {
+	case store.OperationReset:
+		err = h.Storage.RestoreArchive(c.chatId, op.ArchiveId)
+		text = c.printer.T("*Restored* the list")
+	case store.OperationRemove:
+		var links []string
+		for _, p := range op.Participants {
+			if _, err = h.Storage.Create(p); err != nil {
+				break
+			}
+			links = append(links, withOwner(c.printer, p, p.Link()))
+		}
+		text = c.printer.T("*Restored* %s", store.Escape(strings.Join(links, ", ")))
+	}
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	h.replyWithList(c, text)
+}
+
+func (h *MessageHandler) history(c conversation) {
+	limit := defaultHistoryLength
+	if c.args != "" {
+		limit, _ = strconv.Atoi(c.args)
+		if limit < 1 || limit > maxHistoryLength {
+			h.reply(c, c.printer.T("History length must be from 1 to *%v*", maxHistoryLength))
+			return
+		}
+	}
+
+	archive, err := h.Storage.FindArchive(c.chatId)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	if len(archive) == 0 {
+		h.reply(c, c.printer.T("No past events"))
+		return
+	}
+	if len(archive) > limit {
+		archive = archive[len(archive)-limit:]
+	}
+
+	text := c.printer.T("Past events:\n")
+	for i := len(archive) - 1; i >= 0; i-- {
+		a := archive[i]
+		held := a.Event.Start
+		if held.IsZero() {
+			held = a.Time
+		}
+		going := len(mainList(a.Participants, capacity(c.settings, a.Event)))
+		text = text + c.printer.N(" *%s* %s, %d going\n", going,
+			store.Escape(eventName(c.printer, a.Event)), c.printer.Date(held), going)
+	}
+	h.reply(c, text)
+}
+
+// stats shows the attendance of the active event: the number of times
+// the member was in the main list, the current and the best streak
+func (h *MessageHandler) stats(c conversation) {
+	archive, err := h.Storage.FindArchive(c.chatId)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	type attendance struct {
+		name    string
+		count   int
+		current int
+		best    int
+	}
+	members := map[string]*attendance{}
+	var order []string
+	events := 0
+
+	for _, a := range archive {
+		if a.Event.Id != c.event.Id {
+			continue
+		}
+		events++
+		main := mainList(a.Participants, capacity(c.settings, a.Event))
+		attended := map[string]bool{}
+		for _, p := range a.Participants {
+			if !main[p.Id()] {
+				continue
+			}
+			key := attendanceKey(p.User)
+			attended[key] = true
+			m, ok := members[key]
+			if !ok {
+				m = &attendance{}
+				members[key] = m
+				order = append(order, key)
+			}
+			m.name = p.Name()
+		}
+		for key, m := range members {
+			if attended[key] {
+				m.count++
+				m.current++
+				if m.current > m.best {
+					m.best = m.current
+				}
+			} else {
+				m.current = 0
+			}
+		}
+	}
+
+	if events == 0 {
+		h.reply(c, c.printer.T("No past events"))
+		return
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return members[order[i]].count > members[order[j]].count
+	})
+	text := c.printer.N("*%s*, attendance of %d events:\n", events, store.Escape(eventName(c.printer, c.event)), events)
+	for i, key := range order {
+		m := members[key]
+		text = text + c.printer.T(" *%v)* %s - %v, streak %v, best %v\n", i+1, store.Escape(m.name), m.count, m.current, m.best)
+	}
+	h.reply(c, text)
+}
+
+// attendanceKey identifies the user in the archive,
+// the one added by link is the same as the resolved one
+func attendanceKey(u store.User) string {
+	if u.UserName != "" && u.Type != store.UserGuest {
+		return "@" + strings.ToLower(u.UserName)
+	}
+	return u.Uid()
+}
+
+// export sends the list of the active event as a CSV document
+// to the requester in private or to the chat if the bot can't write there
+func (h *MessageHandler) export(c conversation) {
+	participants, err := h.Storage.FindByEvent(c.event)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	// the join times are in the time zone of the chat
+	location := c.printer.Location()
+	for i := range participants {
+		participants[i].Time = participants[i].Time.In(location)
+	}
+
+	var buf bytes.Buffer
+	data := store.ChatExport{ChatId: c.chatId, Events: []store.Event{c.event}, Participants: participants}
+	if err = store.WriteCSV(&buf, data); err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	name := eventName(c.printer, c.event)
+	file := tgbotapi.FileBytes{Name: fileName(name) + ".csv", Bytes: buf.Bytes()}
+	doc := tgbotapi.NewDocumentUpload(int64(c.user.ID), file)
+	doc.Caption = name
+	if _, err = h.Bot.Send(doc); err == nil {
+		if doc.ChatID != c.chatId {
+			h.confirm(c.chatId, c.printer.T("*Sent* to you in private"))
+		}
+		return
+	}
+	log.Print(err)
+
+	doc.ChatID = c.chatId
+	doc.ReplyToMessageID = c.message.MessageID
+	if _, err = h.Bot.Send(doc); err != nil {
+		h.replyError(c, err)
+	}
+}
+
+// fileName makes the name safe for the file, e.g. "Board games" becomes "board_games"
+func fileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, name)
+}
+
+func (h *MessageHandler) eventList(c conversation) {
+	events, err := h.Storage.FindEvents(c.chatId)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	if len(events) == 0 || !events[0].IsDefault() {
+		events = append([]store.Event{{ChatId: c.chatId}}, events...)
+	}
+
+	text := c.printer.T("Events:\n")
+	for _, e := range events {
+		text = text + eventLine(c.printer, e, c.event)
+	}
+	h.reply(c, text)
+}
+
+func (h *MessageHandler) eventNew(c conversation) {
+
+	match := c.checker.FindStringSubmatch(c.args)
+	if len(match) != 2 {
+		h.reply(c, c.printer.T("Error"))
+		return
+	}
+
+	event, err := h.Storage.CreateEvent(c.chatId, match[1])
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	c.event = event
+	h.replyWithList(c, c.printer.T("*Created* %s", store.Escape(eventName(c.printer, event))))
+}
+
+func (h *MessageHandler) eventSwitchByNumber(c conversation) {
+
+	match := c.checker.FindStringSubmatch(c.args)
+	if len(match) != 2 {
+		h.reply(c, c.printer.T("Error"))
+		return
+	}
+	number, err := strconv.Atoi(match[1])
+	if err != nil {
+		h.reply(c, c.printer.T("Wrong parameter"))
+		return
+	}
+
+	h.switchEvent(c, number)
+}
+
+func (h *MessageHandler) eventSwitchByTitle(c conversation) {
+
+	match := c.checker.FindStringSubmatch(c.args)
+	if len(match) != 2 {
+		h.reply(c, c.printer.T("Error"))
+		return
+	}
+
+	events, err := h.Storage.FindEvents(c.chatId)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	for _, e := range events {
+		if strings.EqualFold(e.Title, match[1]) {
+			h.switchEvent(c, e.Id)
+			return
+		}
+	}
+	h.reply(c, store.Escape(c.printer.T("Event \"%s\" not found", match[1])))
+}
+
+func (h *MessageHandler) switchEvent(c conversation, eventId int) {
+	event, err := h.Storage.SwitchEvent(c.chatId, eventId)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	c.event = event
+	h.replyWithList(c, c.printer.T("*Switched* to %s", store.Escape(eventName(c.printer, event))))
+}
+
+func eventLine(p i18n.Printer, event store.Event, active store.Event) string {
+	line := fmt.Sprintf(" *%v)* %v", event.Id, store.Escape(eventName(p, event)))
+	if event.Id == active.Id {
+		line = line + p.T(" _(active)_")
+	}
+	return line + "\n"
+}
+
+func (h *MessageHandler) setTitle(c conversation) {
+	c.event.Title = clearable(c.args)
+	h.saveEvent(c, "Title")
+}
+
+// setStart sets the date like "tomorrow 19:30" in the time zone of the chat.
+// The guessed date is echoed, so the user can correct it.
+func (h *MessageHandler) setStart(c conversation) {
+	start := time.Time{}
+	ambiguous := false
+	now := h.now().In(c.printer.Location())
+	if value := clearable(c.args); value != "" {
+		var err error
+		if start, ambiguous, err = parseDate(value, now); err != nil {
+			h.reply(c, store.Escape(c.printer.T("Wrong date, use format like: tomorrow 19:30, fri 7pm, 24.10 18:00 or %s",
+				now.Format(startInputLayouts[0]))))
+			return
+		}
+	}
+	c.event.Start = start
+	if !ambiguous {
+		h.saveEvent(c, "Date")
+		return
+	}
+
+	if err := h.Storage.SaveEvent(c.event); err != nil {
+		h.replyError(c, err)
+		return
+	}
+	h.reply(c, c.printer.T("*Date updated:* %s, send /when again if it is wrong", c.printer.Date(start)))
+	h.refreshList(c)
+}
+
+func (h *MessageHandler) setLocation(c conversation) {
+	c.event.Location = clearable(c.args)
+	h.saveEvent(c, "Location")
+}
+
+func (h *MessageHandler) setDescription(c conversation) {
+	c.event.Description = clearable(c.args)
+	h.saveEvent(c, "Description")
+}
+
+func (h *MessageHandler) saveEvent(c conversation, field string) {
+	if err := h.Storage.SaveEvent(c.event); err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	h.replyWithList(c, c.printer.T("*"+field+" updated*"))
+}
+
+// helpCommands are the lines of /help, the descriptions are translated
+var helpCommands = []struct{ commands, description string }{
+	{"/list", "participants list"},
+	{"/pin, /unpin", "pin the list message"},
+	{"/add", "add yourself or someone"},
+	{"/rm", "remove yourself or someone"},
+	{"/maybe, /no", "answer maybe or not going"},
+	{"/reset", "remove all, the list is kept in the history"},
+	{"/undo", "restore the list after the last reset or removal"},
+	{"/history", "past events"},
+	{"/stats", "attendance of the event"},
+	{"/export", "the list as a CSV file"},
+	{"/admin", "commands only administrators can use"},
+	{"/settings", "settings of the chat"},
+	{"/lang", "language of the bot"},
+	{"/event", "events of the chat"},
+	{"/title, /when, /where, /about", "event details"},
+	{"/capacity", "size of the list, the rest are waitlisted"},
+	{"/repeat", "reopen the list every week"},
+	{"/remind", "remind before the start"},
+	{"/ping", "turn to those who have not answered or said maybe"},
+	{"/help", "help"},
+}
+
+const helpExamples = "``` /add @smith\n" +
+	" /add My brother John\n" +
+	" /add +2\n" +
+	" /add +1 Kate\n" +
+	" /rm @smith\n" +
+	" /rm My brother John\n" +
+	" /rm 3\n" +
+	" /event new Board games\n" +
+	" /event switch 2\n" +
+	" /when 2020-05-17 19:30\n" +
+	" /when tomorrow 19:30\n" +
+	" /where -\n" +
+	" /capacity 12\n" +
+	" /repeat tue 19:00 2h\n" +
+	" /remind 24h 1h ping\n" +
+	" /admin reset rm title\n" +
+	" /lang ru\n" +
+	"```\n"
+
+// helpNotes explain the examples
+var helpNotes = []string{
+	"`/add +2` brings two guests, they leave the list together with you",
+	"`/rm 3` removes the third participant",
+	"`/event switch 2` makes the second event active",
+	"`-` clears the event detail",
+	"`/repeat tue 19:00 2h` resets the list every Tuesday 2 hours after 19:00",
+	"`ping` in reminders turns to those who have not answered",
+	"`/admin reset rm title` lets only administrators reset the list, remove others and change the title",
+	"`/lang ru` switches the bot to Russian",
+}
+
+func (h *MessageHandler) help(c conversation) {
+	text := c.printer.T("*Help:*\n")
+	for _, line := range helpCommands {
+		text = text + line.commands + " - " + c.printer.T(line.description) + "\n"
+	}
+	text = text + "\n" + c.printer.T("*Examples:*\n") + helpExamples
+
+	var notes []string
+	for _, note := range helpNotes {
+		notes = append(notes, c.printer.T(note))
+	}
+	text = text + strings.Join(notes, ", ") + "\n\n" + c.printer.T("_Version: %s_", h.Version)
+	h.reply(c, text)
+}
+
+func (h *MessageHandler) ping(c conversation) {
+	mentions, err := h.nonParticipants(c.event)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+
+	if len(mentions) == 0 {
+		h.reply(c, c.printer.T("Everyone known has already signed up"))
+		return
+	}
+
+	h.sendMentions(c.chatId, c.printer.T("*%s* is waiting for you:", store.Escape(eventName(c.printer, c.event))), mentions)
+}
+
+// nonParticipants returns mentions of the known chat members
+// who have not answered or said maybe
+func (h *MessageHandler) nonParticipants(event store.Event) (mentions []string, err error) {
+	h.rememberAdministrators(event.ChatId)
+
+	members, err := h.Storage.FindMembers(event.ChatId)
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := h.Storage.FindByEvent(event)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := map[string]bool{}
+	for _, p := range participants {
+		if p.State() == store.StatusMaybe {
+			continue
+		}
+		signed[p.User.Uid()] = true
+		signed[p.Link()] = true
+	}
+
+	for _, m := range members {
+		if signed[m.User.Uid()] || (m.User.UserName != "" && signed[m.User.Link()]) {
+			continue
+		}
+		mentions = append(mentions, mention(m.User))
+	}
+	return mentions, nil
+}
+
+// sendMentions splits the mentions into messages that fit the Telegram limits
+func (h *MessageHandler) sendMentions(chatId int64, header string, mentions []string) {
+	text := header + "\n"
+	count := 0
+	for _, m := range mentions {
+		if count == mentionsPerMessage || len(text)+len(m)+2 > maxMessageLength {
+			h.sendMessageToChat(chatId, text)
+			text = header + "\n"
+			count = 0
+		}
+		text = text + m + "\n"
+		count++
+	}
+	h.sendMessageToChat(chatId, text)
+}
+
+// remind mentions the participants before the start of the event
+// and posts the current list
+func (h *MessageHandler) remind(event store.Event, now time.Time) {
+	settings := h.settings(event.ChatId)
+	p := h.printer(settings, nil)
+	header := p.T("*%s* starts in %s, %s", store.Escape(eventName(p, event)),
+		store.ShortDuration(event.Start.Sub(now).Round(time.Minute)), p.Date(event.Start))
+
+	participants, err := h.Storage.FindByEvent(event)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	var mentions []string
+	main := mainList(participants, capacity(settings, event))
+	for _, p := range participants {
+		if main[p.Id()] && p.User.Type != store.UserGuest {
+			mentions = append(mentions, mention(p.User))
+		}
+	}
+	if len(mentions) > 0 {
+		h.sendMentions(event.ChatId, header, mentions)
+	} else {
+		h.sendMessageToChat(event.ChatId, header)
+	}
+
+	if event.RemindPing {
+		nonParticipants, err := h.nonParticipants(event)
+		if err != nil {
+			log.Print(err)
+		} else if len(nonParticipants) > 0 {
+			h.sendMentions(event.ChatId, p.T("You have not answered yet:"), nonParticipants)
+		}
+	}
+
+	h.postList(p, settings, event)
+}
+
+func (h *MessageHandler) setReminders(c conversation) {
+	c.event.Reminders = nil
+	c.event.RemindPing = false
+
+	if clearable(c.args) != "" {
+		for _, arg := range strings.Fields(strings.ToLower(c.args)) {
+			if arg == "ping" {
+				c.event.RemindPing = true
+				continue
+			}
+			offset, err := parseOffset(arg)
+			if err != nil || offset <= 0 || len(c.event.Reminders) == maxReminders {
+				h.reply(c, store.Escape(c.printer.T("Wrong reminders, use up to %d like: 24h 1h ping", maxReminders)))
+				return
+			}
+			c.event.Reminders = append(c.event.Reminders, offset)
+		}
+	}
+
+	h.saveEvent(c, "Reminders")
+}
+
+func (h *MessageHandler) adminCommands(c conversation) {
+	h.reply(c, adminCommandsText(c.printer, c.settings))
+}
+
+// setAdminCommands replaces the admin-only commands of the chat, "-" clears them
+func (h *MessageHandler) setAdminCommands(c conversation) {
+	if !h.requireAdmin(c, c.printer.T("Only administrators can change admin-only commands")) {
+		return
+	}
+
+	known := map[string]bool{}
+	for _, r := range h.routes {
+		known[r.botCommand] = !unrestricted[r.botCommand]
+	}
+	commands := []string{}
+	if c.args != "-" {
+		for _, field := range strings.Fields(c.args) {
+			command := strings.ToLower(strings.TrimPrefix(field, "/"))
+			if !known[command] {
+				h.reply(c, c.printer.T("Command /%s can't be admin-only", store.Escape(command)))
+				return
+			}
+			commands = append(commands, command)
+		}
+	}
+
+	settings, err := h.Storage.FindSettings(c.chatId)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	settings.AdminCommands = commands
+	if err = h.Storage.SaveSettings(settings); err != nil {
+		h.replyError(c, err)
+		return
+	}
+	h.reply(c, adminCommandsText(c.printer, settings))
+}
+
+func adminCommandsText(p i18n.Printer, settings store.Settings) string {
+	commands := settings.AdminCommands
+	if commands == nil {
+		commands = store.DefaultAdminCommands
+	}
+	if len(commands) == 0 {
+		return p.T("*Admin-only commands:* none")
+	}
+	return p.T("*Admin-only commands:* %s", "/"+strings.Join(commands, ", /"))
+}
+
+func (h *MessageHandler) language(c conversation) {
+	h.reply(c, c.printer.T("*Language:* %s", c.printer.Lang().Name()))
+}
+
+// setLanguage saves the language of the chat like "/settings language", "-" returns to the default one
+func (h *MessageHandler) setLanguage(c conversation) {
+	setting, _ := findSetting("language")
+	if settings, ok := h.changeSetting(c, setting, c.args); ok {
+		h.language(h.withSettings(c, settings))
+	}
+}
+
+// printer returns the translations to the language set by /lang. The private
+// chat falls back to the language of the Telegram client of the sender or of the
+// member saved earlier when there is no sender, the others to English.
+func (h *MessageHandler) printer(settings store.Settings, sender *tgbotapi.User) i18n.Printer {
+	location := settings.Location()
+	if lang, ok := i18n.Parse(settings.Language); ok {
+		return i18n.New(lang).In(location)
+	}
+
+	chatId := settings.ChatId
+	if chatId > 0 { // the id of the private chat is the id of the user
+		languageCode := ""
+		if sender != nil && int64(sender.ID) == chatId {
+			languageCode = sender.LanguageCode
+		} else {
+			members, err := h.Storage.FindMembers(chatId)
+			if err != nil {
+				log.Print(err)
+			}
+			for _, m := range members {
+				if m.User.Id == strconv.FormatInt(chatId, 10) {
+					languageCode = m.LanguageCode
+				}
+			}
+		}
+		if lang, ok := i18n.Parse(languageCode); ok {
+			return i18n.New(lang).In(location)
+		}
+	}
+	return i18n.New(i18n.Default).In(location)
+}
+
+// withSettings returns the conversation with the changed settings of the chat
+func (h *MessageHandler) withSettings(c conversation, settings store.Settings) conversation {
+	c.settings = settings
+	c.printer = h.printer(settings, c.user)
+	return c
+}
+
+func (h *MessageHandler) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
+
+// allowed tells if the sender can use the command in the chat, the denial is replied otherwise
+func (h *MessageHandler) allowed(c conversation, command string, denial string) bool {
+	if !c.settings.AdminOnly(command) {
+		return true
+	}
+	return h.requireAdmin(c, denial)
+}
+
+// requireAdmin replies the denial unless the sender is an administrator
+func (h *MessageHandler) requireAdmin(c conversation, denial string) bool {
+	admin, err := h.isAdmin(c)
+	if err != nil {
+		h.replyError(c, err)
+		return false
+	}
+	if !admin {
+		h.reply(c, store.Escape(denial))
+	}
+	return admin
+}
+
+// isAdmin tells if the sender is an owner of the bot or an administrator of the
+// chat. Everyone is the administrator of the private chat with the bot.
+func (h *MessageHandler) isAdmin(c conversation) (bool, error) {
+	for _, id := range h.Owners {
+		if id == c.user.ID {
+			return true, nil
+		}
+	}
+	if c.message != nil && c.message.Chat != nil && c.message.Chat.IsPrivate() {
+		return true, nil
+	}
+
+	administrators, err := h.Bot.GetChatAdministrators(tgbotapi.ChatConfig{ChatID: c.chatId})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get administrators")
+	}
+	for _, a := range administrators {
+		if a.User != nil && a.User.ID == c.user.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isSender tells if the participant is the one who sent the command
+func isSender(c conversation, participant store.Participant) bool {
+	switch participant.User.Type {
+	case store.UserTelegram:
+		return participant.User.Id == strconv.Itoa(c.user.ID)
+	case store.UserUnresolved:
+		return c.user.UserName != "" && strings.EqualFold(participant.User.UserName, c.user.UserName)
+	}
+	return false
+}
+
+// rememberMembers keeps track of the chat members for ping
+func (h *MessageHandler) rememberMembers(message *tgbotapi.Message) {
+	chatId := message.Chat.ID
+	users := []tgbotapi.User{}
+	if message.From != nil {
+		users = append(users, *message.From)
+	}
+	if message.NewChatMembers != nil {
+		users = append(users, *message.NewChatMembers...)
+	}
+	for _, u := range users {
+		h.rememberMember(chatId, u)
+	}
+
+	if message.LeftChatMember != nil {
+		member := store.Member{User: telegramUser(message.LeftChatMember), ChatId: chatId}
+		h.membersMu.Lock()
+		delete(h.members, memberKey(member))
+		h.membersMu.Unlock()
+		if err := h.Storage.DeleteMember(member); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+func (h *MessageHandler) rememberAdministrators(chatId int64) {
+	administrators, err := h.Bot.GetChatAdministrators(tgbotapi.ChatConfig{ChatID: chatId})
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	for _, a := range administrators {
+		if a.User != nil {
+			h.rememberMember(chatId, *a.User)
+		}
+	}
+}
+
+func (h *MessageHandler) rememberMember(chatId int64, user tgbotapi.User) {
+	if user.IsBot {
+		return
+	}
+	member := store.Member{User: telegramUser(&user), Time: h.now(), ChatId: chatId, LanguageCode: user.LanguageCode}
+	key := memberKey(member)
+
+	h.membersMu.Lock()
+	defer h.membersMu.Unlock()
+	if saved, ok := h.members[key]; ok && saved.User == member.User && saved.LanguageCode == member.LanguageCode {
+		return
+	}
+	if err := h.Storage.SaveMember(member); err != nil {
+		log.Print(err)
+		return
+	}
+	if h.members == nil {
+		h.members = map[string]store.Member{}
+	}
+	h.members[key] = member
+}
+
+func memberKey(member store.Member) string {
+	return strconv.FormatInt(member.ChatId, 10) + "/" + member.Id()
+}
+
+func (h *MessageHandler) participantsText(p i18n.Printer, settings store.Settings, event store.Event) (text string, err error) {
+	compact := listFormat(settings) == store.FormatCompact
+	if compact {
+		text = compactEventText(p, event)
+	} else {
+		text = eventText(p, event)
+	}
+	participants, err := h.Storage.FindByEvent(event)
+	if err != nil {
+		return "", err
+	}
+	if len(participants) == 0 {
+		return text + p.T("No participants"), nil
+	}
+
+	limit := capacity(settings, event)
+	counts := map[store.Status]int{}
+	for _, participant := range participants {
+		counts[participant.State()]++
+	}
+	waitlisted := 0
+	if counts[store.StatusGoing] > limit {
+		waitlisted = counts[store.StatusGoing] - limit
+	}
+
+	totals := []string{p.N("%d going", counts[store.StatusGoing]-waitlisted, counts[store.StatusGoing]-waitlisted)}
+	if waitlisted > 0 {
+		totals = append(totals, p.N("%d waitlisted", waitlisted, waitlisted))
+	}
+	totals = append(totals, p.N("%d maybe", counts[store.StatusMaybe], counts[store.StatusMaybe]),
+		p.N("%d declined", counts[store.StatusDeclined], counts[store.StatusDeclined]))
+	if !compact {
+		text = text + "_" + strings.Join(totals, ", ") + "_\n"
+	}
+
+	sections := map[store.Status]string{
+		store.StatusMaybe:    p.T("Maybe:\n"),
+		store.StatusDeclined: p.T("Declined:\n"),
+	}
+	if event.Capacity > 0 || settings.Capacity > 0 {
+		sections[store.StatusGoing] = p.T("Participants (%v/%v):\n", counts[store.StatusGoing]-waitlisted, limit)
+	} else {
+		sections[store.StatusGoing] = p.T("Participants:\n")
+	}
+
+	previous := store.Status("")
+	numbers := listNumbers(participants, limit)
+	for i, participant := range participants {
+		if participant.State() != previous {
+			text = text + sections[participant.State()]
+			previous = participant.State()
+		}
+		if i == limit && participant.IsGoing() {
+			text = text + p.T("Waitlist:\n")
+		}
+		text = text + fmt.Sprintf(" *%v)* %v\n", numbers[i], store.Escape(withOwner(p, participant, participant.Name())))
+	}
+	return text, nil
+}
+
+// listNumbers returns the numbers of the participants in the list. The waitlist
+// is numbered on its own like w1, w2, the rest go on after the main list.
+func listNumbers(participants []store.Participant, limit int) []string {
+	numbers := make([]string, len(participants))
+	number, waitlisted := 0, 0
+	for i, p := range participants {
+		if p.IsGoing() && i >= limit {
+			waitlisted++
+			numbers[i] = "w" + strconv.Itoa(waitlisted)
+			continue
+		}
+		number++
+		numbers[i] = strconv.Itoa(number)
+	}
+	return numbers
+}
+
+// capacity returns the size of the main list, participants beyond it are waitlisted.
+// The list without its own capacity has the one of the chat settings.
+func capacity(settings store.Settings, event store.Event) int {
+	if event.Capacity > 0 {
+		return event.Capacity
+	}
+	if settings.Capacity > 0 {
+		return settings.Capacity
+	}
+	return maxParticipants
+}
+
+func going(participants []store.Participant) (res []store.Participant) {
+	for _, p := range participants {
+		if p.IsGoing() {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// mainList returns ids of the going participants who fit into the capacity
+func mainList(participants []store.Participant, limit int) map[string]bool {
+	res := map[string]bool{}
+	for i, p := range going(participants) {
+		if i < limit {
+			res[p.Id()] = true
+		}
+	}
+	return res
+}
+
+// promotedText mentions the participants moved from the waitlist to the main list
+func promotedText(printer i18n.Printer, before []store.Participant, after []store.Participant, limit int) (text string) {
+	wasGoing := mainList(before, len(before))
+	wasMain := mainList(before, limit)
+	for id := range mainList(after, limit) {
+		if wasGoing[id] && !wasMain[id] {
+			for _, p := range after {
+				if p.Id() == id {
+					text = text + printer.T("*Promoted from the waitlist* %s", mention(p.User)) + "\n"
+				}
+			}
+		}
+	}
+	return text
+}
+
+// mention returns a markdown mention that notifies the user
+func mention(u store.User) string {
+	if u.Type == store.UserTelegram && u.UserName == "" {
+		return fmt.Sprintf("[%s](tg://user?id=%s)", store.Escape(u.Name()), u.Id)
+	}
+	return store.Escape(u.Link())
+}
+
+func telegramUser(u *tgbotapi.User) store.User {
+	return store.User{
+		Id:        strconv.Itoa(u.ID),
+		UserName:  u.UserName,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Type:      store.UserTelegram,
+	}
+}
+
+func eventText(p i18n.Printer, event store.Event) (text string) {
+	if !event.HasDetails() && event.IsDefault() {
+		return ""
+	}
+	text = fmt.Sprintf("*%s*\n", store.Escape(eventName(p, event)))
+	if !event.Start.IsZero() {
+		text = text + p.T("_When:_ %s\n", p.Date(event.Start))
+	}
+	if event.Location != "" {
+		text = text + p.T("_Where:_ %s\n", store.Escape(event.Location))
+	}
+	if r := event.Recurrence; r != nil {
+		text = text + p.T("_Repeat:_ %s\n", p.T("%s %02d:%02d, reset %s after start",
+			p.T("every "+r.Weekday.String()), r.Hour, r.Minute, store.ShortDuration(r.ResetAfter)))
+	}
+	if len(event.Reminders) > 0 {
+		var reminders []string
+		for _, r := range event.Reminders {
+			reminders = append(reminders, store.ShortDuration(r))
+		}
+		text = text + p.T("_Remind:_ %s before", strings.Join(reminders, ", "))
+		if event.RemindPing {
+			text = text + p.T(", ping the rest")
+		}
+		text = text + "\n"
+	}
+	if event.Description != "" {
+		text = text + store.Escape(event.Description) + "\n"
+	}
+	return text + "\n"
+}
+
+// compactEventText shows the title of the event only
+func compactEventText(p i18n.Printer, event store.Event) string {
+	if event.IsDefault() && event.Title == "" {
+		return ""
+	}
+	return fmt.Sprintf("*%s*\n", store.Escape(eventName(p, event)))
+}
+
+// withOwner adds the one who brought the guest to the name, "John (guest of @smith)"
+func withOwner(p i18n.Printer, participant store.Participant, name string) string {
+	if participant.Owner == nil {
+		return name
+	}
+	return p.T("%s (guest of %s)", name, participant.Owner.Link())
+}
+
+// eventName translates the name of the event without a title
+func eventName(p i18n.Printer, event store.Event) string {
+	if event.Title == "" {
+		return p.T(event.Name())
+	}
+	return event.Name()
+}
+
+// parseOffset parses durations like 90m, 2h or 1d
+func parseOffset(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		return time.Duration(days) * 24 * time.Hour, err
+	}
+	return time.ParseDuration(value)
+}
+
+// clearable returns the argument of a setter command, "-" clears the value
+func clearable(args string) string {
+	if args == "-" {
+		return ""
+	}
+	return args
+}
+
+// reply answers the command with a message or the button with a notification
+func (h *MessageHandler) reply(c conversation, text string) {
+	if c.callback != nil {
+		h.answerCallback(c.callback, plain(text))
+		return
+	}
+	h.sendMessageToChat(c.chatId, text)
+}
+
+// replyError reports the failure to the chat
+func (h *MessageHandler) replyError(c conversation, err error) {
+	h.reply(c, errorText(c.printer, err))
+}
+
+// errorText returns the translated markdown message of the failure caused by the request,
+// the storage failures are logged and reported without the details
+func errorText(p i18n.Printer, err error) string {
+	var e *store.Error
+	if errors.As(err, &e) {
+		// the formats are markdown, the arguments come from the users
+		args := make([]interface{}, len(e.Args))
+		for i, arg := range e.Args {
+			if s, ok := arg.(string); ok {
+				arg = store.Escape(s)
+			}
+			args[i] = arg
+		}
+		return p.T(e.Format, args...)
+	}
+	log.Print(err)
+	return p.T("Something went wrong, please try again")
+}
+
+// replyWithList confirms the change and updates the live list message of the event
+func (h *MessageHandler) replyWithList(c conversation, text string) {
+	if c.callback != nil {
+		h.answerCallback(c.callback, plain(text))
+		if messageId := c.callback.Message.MessageID; messageId != c.event.MessageId {
+			if c.event.MessageId == 0 {
+				h.saveListMessage(c.event, messageId)
+				c.event.MessageId = messageId
+			} else {
+				_ = h.editList(c.printer, c.settings, c.event, messageId)
+			}
+		}
+	} else {
+		h.confirm(c.chatId, text)
+	}
+	h.refreshList(c)
+}
+
+// refreshList edits the live list message or posts a new one if it is gone
+func (h *MessageHandler) refreshList(c conversation) {
+	if c.event.MessageId != 0 && h.editList(c.printer, c.settings, c.event, c.event.MessageId) == nil {
+		return
+	}
+	h.postList(c.printer, c.settings, c.event)
+}
+
+// postList sends the list with the buttons and makes it the live list message
+func (h *MessageHandler) postList(p i18n.Printer, settings store.Settings, event store.Event) {
+	text, err := h.participantsText(p, settings, event)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	keyboard := listKeyboard(p, event)
+	msg := tgbotapi.NewMessage(event.ChatId, text)
+	msg.ParseMode = "markdown"
+	msg.ReplyMarkup = keyboard
+	sent, err := h.Bot.Send(msg)
+	if err != nil {
+		log.Print(err)
+		log.Print(msg.Text)
+		return
+	}
+
+	h.saveListMessage(event, sent.MessageID)
+
+	if event.Pinned {
+		pin := tgbotapi.PinChatMessageConfig{ChatID: event.ChatId, MessageID: sent.MessageID, DisableNotification: true}
+		if _, err := h.Bot.PinChatMessage(pin); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+func (h *MessageHandler) saveListMessage(event store.Event, messageId int) {
+	event.MessageId = messageId
+	if err := h.Storage.SaveEvent(event); err != nil {
+		log.Print(err)
+	}
+}
+
+func (h *MessageHandler) editList(p i18n.Printer, settings store.Settings, event store.Event, messageId int) error {
+	text, err := h.participantsText(p, settings, event)
+	if err != nil {
+		log.Print(err)
+		return err
+	}
+	keyboard := listKeyboard(p, event)
+	edit := tgbotapi.NewEditMessageText(event.ChatId, messageId, text)
+	edit.ParseMode = "markdown"
+	edit.ReplyMarkup = &keyboard
+	_, err = h.Bot.Send(edit)
+	if err != nil && strings.Contains(err.Error(), "message is not modified") {
+		return nil
+	}
+	if err != nil {
+		log.Print(err)
+	}
+	return err
+}
+
+// confirm sends a short confirmation which is deleted after a few seconds
+func (h *MessageHandler) confirm(chatId int64, text string) {
+	msg := tgbotapi.NewMessage(chatId, text)
+	msg.ParseMode = "markdown"
+	sent, err := h.Bot.Send(msg)
+	if err != nil {
+		log.Print(err)
+		log.Print(text)
+		return
+	}
+	time.AfterFunc(confirmationLifetime, func() {
+		h.deleteMessage(chatId, sent.MessageID)
+	})
+}
+
+func (h *MessageHandler) deleteMessage(chatId int64, messageId int) {
+	if _, err := h.Bot.DeleteMessage(tgbotapi.NewDeleteMessage(chatId, messageId)); err != nil {
+		log.Print(err)
+	}
+}
+
+func (h *MessageHandler) answerCallback(query *tgbotapi.CallbackQuery, text string) {
+	if _, err := h.Bot.AnswerCallbackQuery(tgbotapi.NewCallback(query.ID, text)); err != nil {
+		log.Print(err)
+	}
+}
+
+func listKeyboard(p i18n.Printer, event store.Event) tgbotapi.InlineKeyboardMarkup {
+	id := strconv.Itoa(event.Id)
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(p.T("Join"), "join:"+id),
+		tgbotapi.NewInlineKeyboardButtonData(p.T("Maybe"), "maybe:"+id),
+		tgbotapi.NewInlineKeyboardButtonData(p.T("Leave"), "leave:"+id),
+	))
+}
+
+var markdownLink = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+
+// plain removes the markdown from the text for the button notifications
+func plain(text string) string {
+	r := strings.NewReplacer("\\*", "*", "\\`", "`", "\\_", "_", "*", "", "`", "", "_", "")
+	text = r.Replace(markdownLink.ReplaceAllString(text, "$1"))
+	if runes := []rune(text); len(runes) > maxCallbackTextLength {
+		text = string(runes[:maxCallbackTextLength])
 	}
 	return text
 }
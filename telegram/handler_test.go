@@ -0,0 +1,889 @@
+package telegram
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/pkg/errors"
+	"github.com/taras-by/tbot/store"
+)
+
+type testBot struct {
+	t       *testing.T
+	service *BotService
+	sender  *fakeSender
+	storage store.Repository
+	// now is the clock of the bot
+	now time.Time
+}
+
+// testNow is the start time of the test clock, Sunday
+var testNow = time.Date(2020, time.May, 17, 12, 0, 0, 0, time.UTC)
+
+func newTestBot(t *testing.T) *testBot {
+	return newTestBotWithStorage(t, store.NewMemoryStorage())
+}
+
+func newTestBotWithStorage(t *testing.T, storage store.Repository) *testBot {
+	// smith administers the test chat
+	sender := &fakeSender{administrators: []tgbotapi.ChatMember{{User: testUsers["smith"], Status: "creator"}}}
+	bot := &testBot{t: t, sender: sender, storage: storage, now: testNow}
+	// the clock ticks a second on every reading, so the participants keep their order
+	clock := func() time.Time {
+		bot.now = bot.now.Add(time.Second)
+		return bot.now
+	}
+	bot.service = &BotService{
+		Handler: &MessageHandler{Bot: sender, Storage: storage, Version: "test", Now: clock},
+	}
+	bot.service.Init()
+	return bot
+}
+
+// run pushes the updates through the bot service and returns the texts it sent
+func (b *testBot) run(updates ...tgbotapi.Update) []string {
+	from := b.sender.count()
+	b.service.Source = &fakeSource{updates: updates}
+	if err := b.service.Run(); err != nil {
+		b.t.Fatal(err)
+	}
+	return b.sender.texts(from)
+}
+
+// commands runs the lines like "smith: /add". The line without a command like
+// "smith: Yes" presses the button on the last message having it.
+func (b *testBot) commands(lines ...string) (texts []string) {
+	for _, line := range lines {
+		parts := strings.SplitN(line, ": ", 2)
+		if strings.HasPrefix(parts[1], "/") {
+			texts = append(texts, b.run(command(line))...)
+			continue
+		}
+		data, messageId := b.sender.button(parts[1])
+		if data == "" {
+			b.t.Fatalf("button %q not found", parts[1])
+		}
+		texts = append(texts, b.run(press(parts[0], data, messageId))...)
+	}
+	return texts
+}
+
+// answers returns the notifications of the pressed buttons
+func (b *testBot) answers() (answers []string) {
+	for _, a := range b.sender.answers {
+		answers = append(answers, a.Text)
+	}
+	return answers
+}
+
+// participants returns the participants of the active event like "@smith going"
+func (b *testBot) participants() (res []string) {
+	event, err := b.storage.ActiveEvent(testChatId)
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	participants, err := b.storage.FindByEvent(event)
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	for _, p := range participants {
+		res = append(res, fmt.Sprintf("%s %s", p.Link(), p.State()))
+	}
+	return res
+}
+
+func TestRoutes(t *testing.T) {
+	cases := []struct {
+		name         string
+		before       []string
+		command      string
+		reply        string
+		participants []string
+	}{
+		{
+			name:         "add me",
+			command:      "smith: /add",
+			reply:        "*Added* @smith",
+			participants: []string{"@smith going"},
+		},
+		{
+			name:         "add me twice",
+			before:       []string{"smith: /add"},
+			command:      "smith: /add",
+			reply:        "You have already answered: *going*",
+			participants: []string{"@smith going"},
+		},
+		{
+			name:         "add by link",
+			before:       []string{"smith: /add"},
+			command:      "smith: /add @ann",
+			reply:        "*Added* @ann",
+			participants: []string{"@smith going", "@ann going"},
+		},
+		{
+			name:         "add by link resolved by the user",
+			before:       []string{"smith: /add @ann"},
+			command:      "ann: /add",
+			reply:        "*Added* @ann",
+			participants: []string{"@ann going"},
+		},
+		{
+			name:    "add by number",
+			command: "smith: /add 5",
+			reply:   "Fail. UserName as an number",
+		},
+		{
+			name:         "add by name",
+			command:      "smith: /add My brother John",
+			reply:        "*Added* My brother John",
+			participants: []string{"My brother John going"},
+		},
+		{
+			name:         "add by name twice",
+			before:       []string{"ann: /add My brother John"},
+			command:      "ann: /add My brother John",
+			reply:        "My brother John (guest of @ann) is already in the list of participants",
+			participants: []string{"My brother John going"},
+		},
+		{
+			name:    "remove own guest by name",
+			before:  []string{"ann: /add My brother John"},
+			command: "ann: /rm My brother John",
+			reply:   "*Removed* My brother John (guest of @ann)",
+		},
+		{
+			name:         "remove me",
+			before:       []string{"smith: /add", "ann: /add"},
+			command:      "smith: /rm",
+			reply:        "*Removed* @smith",
+			participants: []string{"@ann going"},
+		},
+		{
+			name:    "remove me not a participant",
+			command: "smith: /rm",
+			reply:   "You are not a participant yet",
+		},
+		{
+			name:         "remove by link",
+			before:       []string{"smith: /add", "ann: /add"},
+			command:      "smith: /rm @ann",
+			reply:        "*Removed* @ann",
+			participants: []string{"@smith going"},
+		},
+		{
+			name:    "remove by unknown link",
+			command: "smith: /rm @nobody",
+			reply:   "Participant with link @nobody not found",
+		},
+		{
+			name:         "remove by number",
+			before:       []string{"smith: /add", "ann: /add"},
+			command:      "smith: /rm 1",
+			reply:        "*Removed* @smith",
+			participants: []string{"@ann going"},
+		},
+		{
+			name:    "remove by unknown number",
+			command: "smith: /rm 3",
+			reply:   "Participant with number 3 not found",
+		},
+		{
+			name:    "remove by name",
+			before:  []string{"smith: /add My brother John"},
+			command: "smith: /rm My brother John",
+			reply:   "*Removed* My brother John",
+		},
+		{
+			name:         "maybe",
+			command:      "smith: /maybe",
+			reply:        "*Maybe* @smith",
+			participants: []string{"@smith maybe"},
+		},
+		{
+			name:         "decline",
+			before:       []string{"smith: /add", "ann: /maybe"},
+			command:      "ann: /no",
+			reply:        "_1 going, 0 maybe, 1 declined_",
+			participants: []string{"@smith going", "@ann declined"},
+		},
+		{
+			name:    "list",
+			command: "smith: /list",
+			reply:   "No participants",
+		},
+		{
+			name:    "pin",
+			command: "smith: /pin",
+			reply:   "No participants",
+		},
+		{
+			name:    "unpin",
+			before:  []string{"smith: /pin"},
+			command: "smith: /unpin",
+			reply:   "*Unpinned*",
+		},
+		{
+			name:    "events",
+			command: "smith: /event",
+			reply:   "*0)* Default _(active)_",
+		},
+		{
+			name:    "events list",
+			before:  []string{"smith: /event new Football"},
+			command: "smith: /event list",
+			reply:   "*1)* Football _(active)_",
+		},
+		{
+			name:    "new event",
+			before:  []string{"smith: /add"},
+			command: "smith: /event new Football",
+			reply:   "*Created* Football",
+		},
+		{
+			name:         "switch event by number",
+			before:       []string{"smith: /add", "smith: /event new Football"},
+			command:      "smith: /event switch 0",
+			reply:        "*Switched* to Default",
+			participants: []string{"@smith going"},
+		},
+		{
+			name:    "switch event by title",
+			before:  []string{"smith: /event new Football", "smith: /event switch 0"},
+			command: "smith: /event switch football",
+			reply:   "*Switched* to Football",
+		},
+		{
+			name:    "switch to unknown event",
+			command: "smith: /event switch 7",
+			reply:   "Event 7 not found",
+		},
+		{
+			name:    "title",
+			command: "smith: /title Match",
+			reply:   "*Match*",
+		},
+		{
+			name:    "when",
+			command: "smith: /when 2030-05-17 19:30",
+			reply:   "_When:_ Fri, 17 May 2030 19:30",
+		},
+		{
+			name:    "when wrong",
+			command: "smith: /when someday",
+			reply:   "Wrong date",
+		},
+		{
+			name:    "where",
+			command: "smith: /where Arena",
+			reply:   "_Where:_ Arena",
+		},
+		{
+			name:    "about",
+			command: "smith: /about Bring a ball",
+			reply:   "Bring a ball",
+		},
+		{
+			name:         "capacity",
+			before:       []string{"smith: /capacity 1", "smith: /add"},
+			command:      "ann: /add",
+			reply:        "*Waitlisted* @ann",
+			participants: []string{"@smith going", "@ann going"},
+		},
+		{
+			name:         "capacity promotion",
+			before:       []string{"smith: /capacity 1", "smith: /add", "ann: /add"},
+			command:      "smith: /rm",
+			reply:        "*Promoted from the waitlist* @ann",
+			participants: []string{"@ann going"},
+		},
+		{
+			name:         "waitlist numbers",
+			before:       []string{"smith: /capacity 1", "smith: /add", "ann: /add", "bob: /maybe"},
+			command:      "smith: /list",
+			reply:        "Participants (1/1):\n *1)* John Smith\nWaitlist:\n *w1)* Ann\nMaybe:\n *2)* Bob\n",
+			participants: []string{"@smith going", "@ann going", "Bob maybe"},
+		},
+		{
+			name:         "remove from the waitlist by number",
+			before:       []string{"smith: /capacity 1", "smith: /add", "ann: /add", "bob: /maybe"},
+			command:      "smith: /rm w1",
+			reply:        "*Removed* @ann",
+			participants: []string{"@smith going", "Bob maybe"},
+		},
+		{
+			name:         "remove after the waitlist by number",
+			before:       []string{"smith: /capacity 1", "smith: /add", "ann: /add", "bob: /maybe"},
+			command:      "smith: /rm 2",
+			reply:        "*Removed* Bob",
+			participants: []string{"@smith going", "@ann going"},
+		},
+		{
+			name:         "remove from the waitlist by wrong number",
+			before:       []string{"smith: /capacity 1", "smith: /add", "ann: /add"},
+			command:      "smith: /rm w2",
+			reply:        "Participant with number w2 not found",
+			participants: []string{"@smith going", "@ann going"},
+		},
+		{
+			name:    "capacity wrong",
+			command: "smith: /capacity 0",
+			reply:   "Capacity must be from 1 to *100*",
+		},
+		{
+			name:    "repeat",
+			command: "smith: /repeat tue 19:00 2h",
+			reply:   "_Repeat:_ every Tuesday 19:00, reset 2h after start",
+		},
+		{
+			name:    "repeat wrong",
+			command: "smith: /repeat someday 19:00",
+			reply:   "Wrong schedule",
+		},
+		{
+			name:    "repeat clear",
+			before:  []string{"smith: /repeat tue 19:00"},
+			command: "smith: /repeat -",
+			reply:   "*Schedule updated*",
+		},
+		{
+			name:    "remind",
+			command: "smith: /remind 24h 1h ping",
+			reply:   "_Remind:_ 24h, 1h before, ping the rest",
+		},
+		{
+			name:    "remind wrong",
+			command: "smith: /remind soon",
+			reply:   "Wrong reminders",
+		},
+		{
+			name:         "ping",
+			before:       []string{"smith: /add", "ann: /list", "bob: /no"},
+			command:      "smith: /ping",
+			reply:        "*Default* is waiting for you:\n@ann\n",
+			participants: []string{"@smith going", "Bob declined"},
+		},
+		{
+			name:         "ping everyone signed up",
+			before:       []string{"smith: /add"},
+			command:      "smith: /ping",
+			reply:        "Everyone known has already signed up",
+			participants: []string{"@smith going"},
+		},
+		{
+			name:         "reset",
+			before:       []string{"smith: /add", "ann: /add"},
+			command:      "smith: /reset",
+			reply:        "Are you sure? All participants of *Default* will be removed",
+			participants: []string{"@smith going", "@ann going"},
+		},
+		{
+			name:    "reset confirmed",
+			before:  []string{"smith: /add", "ann: /add", "smith: /reset"},
+			command: "smith: Yes",
+			reply:   "All participants was deleted",
+		},
+		{
+			name:    "history",
+			before:  []string{"smith: /add", "smith: /reset", "smith: Yes"},
+			command: "smith: /history",
+			reply:   "*Default*",
+		},
+		{
+			name:    "history empty",
+			command: "smith: /history 3",
+			reply:   "No past events",
+		},
+		{
+			name:    "stats",
+			before:  []string{"smith: /add", "smith: /reset", "smith: Yes", "smith: /add", "ann: /add", "smith: /reset", "smith: Yes"},
+			command: "smith: /stats",
+			reply:   "*1)* John Smith - 2, streak 2, best 2",
+		},
+		{
+			name:    "help",
+			command: "smith: /help",
+			reply:   "*Help:*",
+		},
+		{
+			name:    "help on ping",
+			command: "smith: /help",
+			reply:   "/ping - turn to those who have not answered or said maybe",
+		},
+		{
+			name:    "start",
+			command: "smith: /start",
+			reply:   "*Help:*",
+		},
+		{
+			name:    "wrong command",
+			command: "smith: /unknown",
+			reply:   "Wrong command",
+		},
+		{
+			name:    "parameter too long",
+			command: "smith: /add " + strings.Repeat("a", maxLengthStringArgument+1),
+			reply:   "Parameter too long",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bot := newTestBot(t)
+			bot.commands(tc.before...)
+
+			texts := bot.commands(tc.command)
+			if !containsText(texts, tc.reply) {
+				t.Errorf("reply %q not found in %q", tc.reply, texts)
+			}
+			if got := bot.participants(); strings.Join(got, ", ") != strings.Join(tc.participants, ", ") {
+				t.Errorf("participants %q, want %q", got, tc.participants)
+			}
+		})
+	}
+}
+
+func TestButtons(t *testing.T) {
+	bot := newTestBot(t)
+	bot.commands("smith: /capacity 1", "smith: /list")
+	event, _ := bot.storage.ActiveEvent(testChatId)
+
+	bot.run(press("smith", "join:0", event.MessageId))
+	bot.run(press("ann", "join:0", event.MessageId))
+	bot.run(press("bob", "maybe:0", event.MessageId))
+	if got, want := strings.Join(bot.participants(), ", "), "@smith going, @ann going, Bob maybe"; got != want {
+		t.Errorf("participants %q, want %q", got, want)
+	}
+
+	texts := bot.run(press("smith", "leave:0", event.MessageId))
+	if !containsText(texts, "*Promoted from the waitlist* @ann") {
+		t.Errorf("promotion not found in %q", texts)
+	}
+
+	bot.run(press("smith", "unknown:0", event.MessageId))
+	answers := bot.answers()
+	want := []string{"Added @smith", "Waitlisted @ann", "Maybe Bob", "Removed @smith", "Wrong button"}
+	if strings.Join(answers, ", ") != strings.Join(want, ", ") {
+		t.Errorf("answers %q, want %q", answers, want)
+	}
+}
+
+func TestLiveList(t *testing.T) {
+	bot := newTestBot(t)
+	bot.commands("smith: /pin")
+	event, _ := bot.storage.ActiveEvent(testChatId)
+	if event.MessageId == 0 || len(bot.sender.pinned) != 1 || bot.sender.pinned[0] != event.MessageId {
+		t.Fatalf("live message %d is not pinned: %v", event.MessageId, bot.sender.pinned)
+	}
+
+	from := bot.sender.count()
+	bot.commands("ann: /add")
+	edited := false
+	for _, m := range bot.sender.messages[from:] {
+		if e, ok := m.(tgbotapi.EditMessageTextConfig); ok && e.MessageID == event.MessageId {
+			edited = true
+		}
+	}
+	if !edited {
+		t.Errorf("live message %d is not edited", event.MessageId)
+	}
+}
+
+func TestExport(t *testing.T) {
+	bot := newTestBot(t)
+	bot.commands("smith: /event new Board games", "smith: /add", "ann: /maybe")
+
+	texts := bot.commands("smith: /export")
+	if !containsText(texts, "*Sent* to you in private") {
+		t.Errorf("confirmation not found in %q", texts)
+	}
+	docs := bot.sender.documents()
+	if len(docs) != 1 || docs[0].ChatID != int64(testUsers["smith"].ID) {
+		t.Fatalf("document is not sent to the requester: %+v", docs)
+	}
+	file := docs[0].File.(tgbotapi.FileBytes)
+	if file.Name != "board_games.csv" {
+		t.Errorf("file name %q", file.Name)
+	}
+	lines := strings.Split(strings.TrimSpace(string(file.Bytes)), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "Board games,1,going,smith,John,Smith") ||
+		!strings.Contains(lines[2], ",2,maybe,ann,Ann,") {
+		t.Errorf("csv %q", lines)
+	}
+}
+
+func TestResetQuestion(t *testing.T) {
+	bot := newTestBot(t)
+	bot.commands("smith: /add", "ann: /add", "smith: /reset", "ann: Yes", "ann: No", "smith: No")
+	if got := bot.participants(); len(got) != 2 {
+		t.Errorf("participants %q after No", got)
+	}
+	question := bot.sender.keyboards[len(bot.sender.keyboards)-1].messageId
+	if deleted := bot.sender.deleted; len(deleted) != 1 || deleted[0] != question {
+		t.Errorf("deleted messages %v, want the question %d", deleted, question)
+	}
+
+	bot.run(press("smith", "reset:0:1", question), press("smith", "cancel:0:1", question))
+	if got := bot.participants(); len(got) != 2 {
+		t.Errorf("participants %q after the expired Yes", got)
+	}
+	want := "Only administrators can use /reset, Only administrators can use /reset, Cancelled, " +
+		"The question has expired, send /reset again, The question has expired, send /reset again"
+	if got := strings.Join(bot.answers(), ", "); got != want {
+		t.Errorf("answers %q, want %q", got, want)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	bot := newTestBot(t)
+	bot.commands("smith: /add", "ann: /add", "smith: /reset", "smith: Yes")
+
+	for _, tc := range []struct {
+		command      string
+		reply        string
+		participants string
+	}{
+		{"ann: /undo", "Only administrators can undo the changes of others", ""},
+		{"smith: /undo", "*Restored* the list", "@smith going, @ann going"},
+		{"smith: /undo", "Nothing to undo", "@smith going, @ann going"},
+		{"ann: /rm", "*Removed* @ann", "@smith going"},
+		{"ann: /undo", "*Restored* @ann", "@smith going, @ann going"},
+	} {
+		if texts := bot.commands(tc.command); !containsText(texts, tc.reply) {
+			t.Errorf("%s: reply %q not found in %q", tc.command, tc.reply, texts)
+		}
+		if got := strings.Join(bot.participants(), ", "); got != tc.participants {
+			t.Errorf("%s: participants %q, want %q", tc.command, got, tc.participants)
+		}
+	}
+	if archive, _ := bot.storage.FindArchive(testChatId); len(archive) != 0 {
+		t.Errorf("undone reset is kept in the history: %v", archive)
+	}
+
+	bot.commands("ann: /rm")
+	bot.now = bot.now.Add(undoWindow + time.Minute)
+	if texts := bot.commands("ann: /undo"); !containsText(texts, "Nothing to undo") {
+		t.Errorf("removal is undone after the window: %q", texts)
+	}
+}
+
+// brokenStorage fails to save the participants
+type brokenStorage struct {
+	*store.MemoryStorage
+}
+
+func (brokenStorage) AddIfAbsent(p store.Participant, capacity int) (store.Participant, store.AddResult, error) {
+	return p, store.Added, errors.New("disk is full")
+}
+
+func TestStorageFailure(t *testing.T) {
+	bot := newTestBotWithStorage(t, brokenStorage{store.NewMemoryStorage()})
+
+	texts := bot.commands("smith: /add")
+	if containsText(texts, "*Added*") || !containsText(texts, "Something went wrong") {
+		t.Errorf("failure is not reported: %q", texts)
+	}
+	texts = bot.commands("smith: /rm 2")
+	if !containsText(texts, "Participant with number 2 not found") {
+		t.Errorf("not found is not reported: %q", texts)
+	}
+}
+
+// countingStorage counts the saved members and the loaded settings
+type countingStorage struct {
+	*store.MemoryStorage
+	counts map[string]int
+}
+
+func (s countingStorage) SaveMember(member store.Member) error {
+	s.counts["save "+member.User.UserName]++
+	return s.MemoryStorage.SaveMember(member)
+}
+
+func (s countingStorage) FindSettings(chatId int64) (store.Settings, error) {
+	s.counts["settings"]++
+	return s.MemoryStorage.FindSettings(chatId)
+}
+
+func TestRememberMembers(t *testing.T) {
+	storage := countingStorage{store.NewMemoryStorage(), map[string]int{}}
+	bot := newTestBotWithStorage(t, storage)
+	bot.commands("ann: /list", "ann: /add", "smith: /list", "ann: Leave")
+	if storage.counts["save ann"] != 1 || storage.counts["save smith"] != 1 {
+		t.Errorf("members are saved %v times", storage.counts)
+	}
+	members, err := storage.FindMembers(testChatId)
+	if err != nil || len(members) != 2 {
+		t.Errorf("members %+v: %v", members, err)
+	}
+}
+
+func TestSettingsLoadedOnce(t *testing.T) {
+	storage := countingStorage{store.NewMemoryStorage(), map[string]int{}}
+	bot := newTestBotWithStorage(t, storage)
+	bot.commands("smith: /add", "ann: /add", "ann: Leave")
+	if n := storage.counts["settings"]; n != 3 {
+		t.Errorf("settings are loaded %d times for 3 updates", n)
+	}
+}
+
+func containsText(texts []string, s string) bool {
+	for _, text := range texts {
+		if strings.Contains(text, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAdminCommands(t *testing.T) {
+	bot := newTestBot(t)
+	bot.commands("smith: /add", "ann: /add", "smith: /add My brother John")
+
+	for _, tc := range []struct {
+		command string
+		reply   string
+	}{
+		{"ann: /reset", "Only administrators can use /reset"},
+		{"ann: /rm @smith", "Only administrators can remove other participants"},
+		{"ann: /rm My brother John", "Only the one who brought the guest or an administrator can remove it"},
+		{"ann: /admin -", "Only administrators can change admin-only commands"},
+		{"ann: /admin", "*Admin-only commands:* /reset, /rm"},
+		{"smith: /admin help", "Command /help can't be admin-only"},
+		{"smith: /admin reset /title add", "*Admin-only commands:* /reset, /title, /add"},
+		{"ann: /title Match", "Only administrators can use /title"},
+		{"ann: /add @bob", "Only administrators can add other participants"},
+		{"ann: /maybe", "*Maybe* @ann"},
+		{"smith: /rm My brother John", "*Removed* My brother John"},
+		{"ann: /rm @smith", "*Removed* @smith"},
+		{"ann: /rm 1", "*Removed* @ann"},
+		{"smith: /reset", "Are you sure?"},
+		{"smith: Yes", "All participants was deleted"},
+	} {
+		if texts := bot.commands(tc.command); !containsText(texts, tc.reply) {
+			t.Errorf("%s: reply %q not found in %q", tc.command, tc.reply, texts)
+		}
+	}
+}
+
+func TestOwners(t *testing.T) {
+	bot := newTestBot(t)
+	bot.service.Handler.Owners = []int{testUsers["ann"].ID}
+	bot.commands("smith: /add")
+
+	if texts := bot.commands("ann: /reset", "ann: Yes"); !containsText(texts, "All participants was deleted") {
+		t.Errorf("owner is denied: %q", texts)
+	}
+	if texts := bot.commands("bob: /admin -"); !containsText(texts, "Only administrators") {
+		t.Errorf("member changed admin-only commands: %q", texts)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	bot := newTestBot(t)
+
+	for _, tc := range []struct {
+		command string
+		reply   string
+	}{
+		{"smith: /lang", "*Language:* English"},
+		{"smith: /lang de", "Unknown language de, use one of: en, ru, be"},
+		{"ann: /lang ru", "Only administrators can change the settings"},
+		{"smith: /lang ru", "*Язык:* Русский"},
+		{"smith: /add", "*Добавлено:* @smith"},
+		{"smith: /list", "_1 идёт, 0 под вопросом, 0 отказов_"},
+		{"ann: /rm 5", "Участник с номером 5 не найден"},
+		{"smith: /lang -", "*Language:* English"},
+	} {
+		if texts := bot.commands(tc.command); !containsText(texts, tc.reply) {
+			t.Errorf("%s: reply %q not found in %q", tc.command, tc.reply, texts)
+		}
+	}
+
+	// the private chat without the setting speaks the language of the client
+	ann := *testUsers["ann"]
+	ann.LanguageCode = "be-BY"
+	private := command("ann: /help")
+	private.Message.From = &ann
+	private.Message.Chat = &tgbotapi.Chat{ID: int64(ann.ID), Type: "private"}
+	if texts := bot.run(private); !containsText(texts, "*Дапамога:*") {
+		t.Errorf("help in the private chat is not translated: %q", texts)
+	}
+	if texts := bot.commands("ann: /help"); !containsText(texts, "*Help:*") {
+		t.Errorf("help in the group is translated: %q", texts)
+	}
+}
+
+func TestChatSettings(t *testing.T) {
+	bot := newTestBot(t)
+	bot.commands("smith: /add", "ann: /add")
+
+	for _, tc := range []struct {
+		command string
+		reply   string
+	}{
+		{"ann: /settings capacity 1", "Only administrators can change the settings"},
+		{"smith: /settings capacity 0", "Capacity must be from 1 to *100*"},
+		{"smith: /settings color red", "Unknown setting color"},
+		{"smith: /settings timezone Mars/Olympus", "Unknown time zone Mars/Olympus, use a name like Europe/Minsk"},
+		{"smith: /settings guests on_off", "Wrong value on\\_off, use on or off"},
+		{"smith: /settings guests maybe", "Wrong value maybe, use on or off"},
+		{"smith: /settings capacity 1", "`capacity` - default size of the lists: *1*"},
+		{"smith: /list", "Participants (1/1):"},
+		{"smith: /settings guests off", "`guests` - adding participants by name: *off*"},
+		{"ann: /add My brother John", "Guests are not allowed in this chat"},
+		{"smith: /settings adminreset off", "*Settings updated*"},
+		{"ann: /reset", "Are you sure?"},
+		{"smith: /settings timezone Europe/Minsk", "`timezone` - time zone of the dates: *Europe/Minsk*"},
+		{"smith: /settings", "`adminreset` - only administrators can reset the list: *off*"},
+		{"smith: adminreset: off", "`adminreset` - only administrators can reset the list: *on*"},
+		{"ann: /reset", "Only administrators can use /reset"},
+		{"smith: format: full", "`format` - layout of the list: *compact*"},
+		{"smith: language: default", "`language` - language of the bot: *English*"},
+		{"smith: language: English", "`language` - язык бота: *Русский*"},
+		{"smith: language: Русский", "`language` - мова бота: *Беларуская*"},
+		{"smith: language: Беларуская", "`language` - language of the bot: *default*"},
+	} {
+		if texts := bot.commands(tc.command); !containsText(texts, tc.reply) {
+			t.Errorf("%s: reply %q not found in %q", tc.command, tc.reply, texts)
+		}
+	}
+
+	if texts := bot.commands("smith: /list"); containsText(texts, "going") {
+		t.Errorf("compact list has the totals: %q", texts)
+	}
+	bot.commands("ann: format: compact")
+	if answers := bot.answers(); answers[len(answers)-1] != "Only administrators can change the settings" {
+		t.Errorf("member changed the settings: %q", answers)
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	bot := newTestBot(t)
+	minsk := time.FixedZone("Europe/Minsk", 3*60*60)
+
+	for _, tc := range []struct {
+		command string
+		reply   string
+		start   time.Time
+	}{
+		{"smith: /settings timezone Europe/Minsk", "*Europe/Minsk*", time.Time{}},
+		{"smith: /when 2020-05-20 19:30", "_When:_ Wed, 20 May 2020 19:30", time.Date(2020, 5, 20, 19, 30, 0, 0, minsk)},
+		{"smith: /when 2020", "Wrong date, use format like: tomorrow 19:30, fri 7pm, 24.10 18:00 or 2020-05-17 15:00", time.Date(2020, 5, 20, 19, 30, 0, 0, minsk)},
+		{"smith: /when tomorrow 7pm", "*Date updated*", time.Date(2020, 5, 18, 19, 0, 0, 0, minsk)},
+		{"smith: /when 24.10 18:00", "*Date updated:* Sat, 24 Oct 2020 18:00, send /when again if it is wrong",
+			time.Date(2020, 10, 24, 18, 0, 0, 0, minsk)},
+		{"smith: /repeat tue 19:00 2h", "_When:_ Tue, 19 May 2020 19:00", time.Date(2020, 5, 19, 19, 0, 0, 0, minsk)},
+	} {
+		if texts := bot.commands(tc.command); !containsText(texts, tc.reply) {
+			t.Errorf("%s: reply %q not found in %q", tc.command, tc.reply, texts)
+		}
+		event, _ := bot.storage.ActiveEvent(testChatId)
+		if !event.Start.Equal(tc.start) {
+			t.Errorf("%s: start %v, want %v", tc.command, event.Start, tc.start)
+		}
+	}
+
+	// the list reopens for the next Tuesday 19:00 in Minsk
+	from := bot.sender.count()
+	scheduler := Scheduler{Handler: bot.service.Handler}
+	scheduler.tick(time.Date(2020, 5, 19, 21, 0, 0, 0, minsk))
+	if texts := bot.sender.texts(from); !containsText(texts, "*Default* is open for Tue, 26 May 2020 19:00") {
+		t.Errorf("restart is not announced in the time zone of the chat: %q", texts)
+	}
+	event, _ := bot.storage.ActiveEvent(testChatId)
+	if want := time.Date(2020, 5, 26, 19, 0, 0, 0, minsk); !event.Start.Equal(want) {
+		t.Errorf("next start %v, want %v", event.Start, want)
+	}
+}
+
+// TestRemindOnce checks the claimed reminder is not sent again
+// by the next tick or by the bot restarted on the same database
+func TestRemindOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bolt.db")
+	storage, err := store.NewBoltStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot := newTestBotWithStorage(t, storage)
+	bot.commands("smith: /add", "smith: /when 2020-05-18 19:00", "smith: /remind 1h")
+	event, _ := storage.ActiveEvent(testChatId)
+
+	reminders := func(bot *testBot, now time.Time) (count int) {
+		from := bot.sender.count()
+		scheduler := Scheduler{Handler: bot.service.Handler}
+		scheduler.tick(now)
+		for _, text := range bot.sender.texts(from) {
+			if strings.Contains(text, "starts in") {
+				count++
+			}
+		}
+		return count
+	}
+
+	if count := reminders(bot, event.Start.Add(-2*time.Hour)); count != 0 {
+		t.Errorf("%d reminders before the time", count)
+	}
+	if count := reminders(bot, event.Start.Add(-30*time.Minute)); count != 1 {
+		t.Errorf("%d reminders, want 1", count)
+	}
+	if count := reminders(bot, event.Start.Add(-29*time.Minute)); count != 0 {
+		t.Errorf("%d reminders on the next tick", count)
+	}
+
+	if err = storage.Close(); err != nil {
+		t.Fatal(err)
+	}
+	storage, err = store.NewBoltStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = storage.Close() }()
+	restarted := newTestBotWithStorage(t, storage)
+	if count := reminders(restarted, event.Start.Add(-28*time.Minute)); count != 0 {
+		t.Errorf("%d reminders after the restart", count)
+	}
+}
+
+func TestGuests(t *testing.T) {
+	bot := newTestBot(t)
+	bot.commands("smith: /add", "ann: /add")
+
+	for _, tc := range []struct {
+		command string
+		reply   string
+	}{
+		{"ann: /add +2", "*Added* +2 (guest of @ann)"},
+		{"ann: /add +1 John", "*Added* John (guest of @ann)"},
+		{"ann: /add +1 John", "John (guest of @ann) is already in the list of participants"},
+		{"ann: /add +2 John", "Add the named guests one by one: /add +1 John"},
+		{"bob: /add +1 John", "*Added* John (guest of Bob)"},
+		{"smith: /list", " *4)* +2 (guest of @ann)"},
+		{"bob: /rm +1", "Only the one who brought the guest or an administrator can remove it"},
+		{"ann: /rm +1", "*Removed* +1 (guest of @ann)"},
+		{"ann: /undo", "*Restored* +1 (guest of @ann)"},
+		{"smith: /settings guestquota 0", "Guest quota must be from 1 to *100*"},
+		{"smith: /settings guestquota 2", "`guestquota` - guests a member can bring: *2*"},
+		{"bob: /add +2", "You can bring 2 guests at most"},
+		{"ann: /rm", "*Removed* @ann, +1 (guest of @ann), +2 (guest of @ann), John (guest of @ann)"},
+		{"smith: /rm John", "*Removed* John (guest of Bob)"},
+	} {
+		if texts := bot.commands(tc.command); !containsText(texts, tc.reply) {
+			t.Errorf("%s: reply %q not found in %q", tc.command, tc.reply, texts)
+		}
+	}
+
+	if participants := bot.participants(); len(participants) != 1 {
+		t.Errorf("the guests are left: %q", participants)
+	}
+
+	// the guests added before the list got full are reported in the same reply
+	bot.commands("smith: /settings guestquota -", "smith: /capacity 1")
+	texts := bot.commands("ann: /add +60")
+	if len(texts) != 2 || !strings.Contains(texts[0], "*Waitlisted* +50 (guest of @ann)\nThe list is full, maximum waitlist: 50") {
+		t.Errorf("partial failure: %q", texts)
+	}
+}
@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+const jobsRetention = 30 * 24 * time.Hour
+
+// Scheduler runs the jobs of the stored events. Every job is claimed
+// in the storage before it runs, so it fires once even after a restart
+type Scheduler struct {
+	Handler  *MessageHandler
+	Interval time.Duration
+}
+
+func (s *Scheduler) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	s.tick(s.Handler.now())
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.tick(now)
+		}
+	}
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	storage := s.Handler.Storage
+
+	events, err := storage.FindAllEvents()
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	for _, e := range events {
+		if e.Start.IsZero() {
+			continue
+		}
+
+		if now.Before(e.Start) && len(e.Reminders) > 0 {
+			// only the last due reminder is sent when several were missed
+			offsets := append([]time.Duration{}, e.Reminders...)
+			sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+			due := false
+			for _, offset := range offsets {
+				if now.Before(e.Start.Add(-offset)) {
+					continue
+				}
+				key := fmt.Sprintf("%d/%d/remind/%d/%s", e.ChatId, e.Id, e.Start.Unix(), offset)
+				claimed, err := storage.ClaimJob(key, now)
+				if err != nil {
+					log.Print(err)
+					continue
+				}
+				due = due || claimed
+			}
+			if due {
+				log.Printf("Remind event %d/%d", e.ChatId, e.Id)
+				s.Handler.remind(e, now)
+			}
+		}
+
+		if e.Recurrence != nil && !now.Before(e.Start.Add(e.Recurrence.ResetAfter)) {
+			log.Printf("Restart event %d/%d", e.ChatId, e.Id)
+			s.Handler.restartEvent(e, now)
+		}
+	}
+
+	if err := storage.DeleteJobs(now.Add(-jobsRetention)); err != nil {
+		log.Print(err)
+	}
+}
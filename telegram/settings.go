@@ -0,0 +1,340 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/taras-by/tbot/i18n"
+	"github.com/taras-by/tbot/store"
+)
+
+// chatSetting is the option of the chat changed by /settings
+type chatSetting struct {
+	key         string
+	description string
+	// value shows the current value
+	value func(p i18n.Printer, s store.Settings) string
+	// set validates and changes the value, "-" restores the default
+	set func(s *store.Settings, value string) error
+	// next returns the value the menu button switches to, nil for the typed values
+	next func(s store.Settings) string
+}
+
+var chatSettings = []chatSetting{
+	{
+		key:         "capacity",
+		description: "default size of the lists",
+		value: func(p i18n.Printer, s store.Settings) string {
+			if s.Capacity == 0 {
+				return p.T("not limited")
+			}
+			return strconv.Itoa(s.Capacity)
+		},
+		set: func(s *store.Settings, value string) error {
+			if value == "-" {
+				s.Capacity = 0
+				return nil
+			}
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > maxParticipants {
+				return store.NewError(store.ErrInvalid, "Capacity must be from 1 to *%v*", maxParticipants)
+			}
+			s.Capacity = limit
+			return nil
+		},
+	},
+	{
+		key:         "language",
+		description: "language of the bot",
+		value: func(p i18n.Printer, s store.Settings) string {
+			if lang, ok := i18n.Parse(s.Language); ok {
+				return lang.Name()
+			}
+			return p.T("default")
+		},
+		set: func(s *store.Settings, value string) error {
+			if value == "-" {
+				s.Language = ""
+				return nil
+			}
+			lang, ok := i18n.Parse(value)
+			if !ok {
+				var codes []string
+				for _, l := range i18n.Languages {
+					codes = append(codes, string(l))
+				}
+				return store.NewError(store.ErrInvalid, "Unknown language %s, use one of: %s", value, strings.Join(codes, ", "))
+			}
+			s.Language = string(lang)
+			return nil
+		},
+		next: func(s store.Settings) string {
+			lang, _ := i18n.Parse(s.Language)
+			for i, l := range i18n.Languages {
+				if l == lang && i+1 < len(i18n.Languages) {
+					return string(i18n.Languages[i+1])
+				}
+			}
+			if lang == "" {
+				return string(i18n.Languages[0])
+			}
+			return "-"
+		},
+	},
+	{
+		key:         "timezone",
+		description: "time zone of the dates",
+		value: func(p i18n.Printer, s store.Settings) string {
+			if s.Timezone == "" {
+				return p.T("default")
+			}
+			return s.Timezone
+		},
+		set: func(s *store.Settings, value string) error {
+			if value == "-" {
+				s.Timezone = ""
+				return nil
+			}
+			location, err := time.LoadLocation(value)
+			if err != nil || value == "Local" {
+				return store.NewError(store.ErrInvalid, "Unknown time zone %s, use a name like Europe/Minsk", value)
+			}
+			s.Timezone = location.String()
+			return nil
+		},
+	},
+	{
+		key:         "guests",
+		description: "adding participants by name",
+		value: func(p i18n.Printer, s store.Settings) string {
+			return onOff(p, !s.NoGuests)
+		},
+		set: func(s *store.Settings, value string) error {
+			on, err := parseOnOff(value, true)
+			s.NoGuests = !on
+			return err
+		},
+		next: func(s store.Settings) string {
+			return nextOnOff(!s.NoGuests)
+		},
+	},
+	{
+		key:         "guestquota",
+		description: "guests a member can bring",
+		value: func(p i18n.Printer, s store.Settings) string {
+			if s.GuestQuota == 0 {
+				return p.T("not limited")
+			}
+			return strconv.Itoa(s.GuestQuota)
+		},
+		set: func(s *store.Settings, value string) error {
+			if value == "-" {
+				s.GuestQuota = 0
+				return nil
+			}
+			quota, err := strconv.Atoi(value)
+			if err != nil || quota < 1 || quota > maxParticipants {
+				return store.NewError(store.ErrInvalid, "Guest quota must be from 1 to *%v*", maxParticipants)
+			}
+			s.GuestQuota = quota
+			return nil
+		},
+	},
+	{
+		key:         "adminreset",
+		description: "only administrators can reset the list",
+		value: func(p i18n.Printer, s store.Settings) string {
+			return onOff(p, s.AdminOnly("reset"))
+		},
+		set: func(s *store.Settings, value string) error {
+			on, err := parseOnOff(value, true)
+			if err == nil {
+				s.SetAdminOnly("reset", on)
+			}
+			return err
+		},
+		next: func(s store.Settings) string {
+			return nextOnOff(s.AdminOnly("reset"))
+		},
+	},
+	{
+		key:         "format",
+		description: "layout of the list",
+		value: func(p i18n.Printer, s store.Settings) string {
+			return p.T(string(listFormat(s)))
+		},
+		set: func(s *store.Settings, value string) error {
+			switch format := store.ListFormat(strings.ToLower(value)); format {
+			case "-", store.FormatFull:
+				s.Format = ""
+			case store.FormatCompact:
+				s.Format = format
+			default:
+				return store.NewError(store.ErrInvalid, "Unknown format %s, use full or compact", value)
+			}
+			return nil
+		},
+		next: func(s store.Settings) string {
+			if listFormat(s) == store.FormatFull {
+				return string(store.FormatCompact)
+			}
+			return string(store.FormatFull)
+		},
+	},
+}
+
+func findSetting(key string) (chatSetting, bool) {
+	for _, setting := range chatSettings {
+		if setting.key == strings.ToLower(key) {
+			return setting, true
+		}
+	}
+	return chatSetting{}, false
+}
+
+func listFormat(s store.Settings) store.ListFormat {
+	if s.Format == "" {
+		return store.FormatFull
+	}
+	return s.Format
+}
+
+func onOff(p i18n.Printer, on bool) string {
+	if on {
+		return p.T("on")
+	}
+	return p.T("off")
+}
+
+func nextOnOff(on bool) string {
+	if on {
+		return "off"
+	}
+	return "on"
+}
+
+// parseOnOff parses on and off, "-" is the default
+func parseOnOff(value string, defaultValue bool) (bool, error) {
+	switch strings.ToLower(value) {
+	case "-":
+		return defaultValue, nil
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return defaultValue, store.NewError(store.ErrInvalid, "Wrong value %s, use on or off", value)
+}
+
+// settings returns the settings of the chat, the defaults if they fail to load
+func (h *MessageHandler) settings(chatId int64) store.Settings {
+	settings, err := h.Storage.FindSettings(chatId)
+	if err != nil {
+		log.Print(err)
+		return store.Settings{ChatId: chatId}
+	}
+	return settings
+}
+
+// showSettings sends the settings with the buttons switching them
+func (h *MessageHandler) showSettings(c conversation) {
+	msg := tgbotapi.NewMessage(c.chatId, settingsText(c.printer, c.settings))
+	msg.ParseMode = "markdown"
+	msg.ReplyMarkup = settingsKeyboard(c.printer, c.event, c.settings)
+	if _, err := h.Bot.Send(msg); err != nil {
+		log.Print(err)
+	}
+}
+
+// setSetting changes the setting like "/settings capacity 20"
+func (h *MessageHandler) setSetting(c conversation) {
+	match := c.checker.FindStringSubmatch(c.args)
+	if len(match) != 3 {
+		h.reply(c, c.printer.T("Error"))
+		return
+	}
+	setting, ok := findSetting(match[1])
+	if !ok {
+		h.reply(c, store.Escape(c.printer.T("Unknown setting %s", match[1])))
+		return
+	}
+	if settings, ok := h.changeSetting(c, setting, match[2]); ok {
+		c = h.withSettings(c, settings)
+		h.reply(c, c.printer.T("*Settings updated*")+"\n"+settingsText(c.printer, settings))
+	}
+}
+
+// toggleSetting switches the setting by the menu button
+// and updates the menu message
+func (h *MessageHandler) toggleSetting(c conversation) {
+	setting, ok := findSetting(c.args)
+	if !ok || setting.next == nil {
+		h.answerCallback(c.callback, c.printer.T("Wrong button"))
+		return
+	}
+	settings, err := h.Storage.FindSettings(c.chatId)
+	if err != nil {
+		h.replyError(c, err)
+		return
+	}
+	settings, ok = h.changeSetting(c, setting, setting.next(settings))
+	if !ok {
+		return
+	}
+
+	c = h.withSettings(c, settings)
+	h.answerCallback(c.callback, plain(c.printer.T("*Settings updated*")))
+	keyboard := settingsKeyboard(c.printer, c.event, settings)
+	edit := tgbotapi.NewEditMessageText(c.chatId, c.callback.Message.MessageID, settingsText(c.printer, settings))
+	edit.ParseMode = "markdown"
+	edit.ReplyMarkup = &keyboard
+	if _, err = h.Bot.Send(edit); err != nil {
+		log.Print(err)
+	}
+}
+
+// changeSetting saves the new value if the sender is an administrator
+func (h *MessageHandler) changeSetting(c conversation, setting chatSetting, value string) (store.Settings, bool) {
+	if !h.requireAdmin(c, c.printer.T("Only administrators can change the settings")) {
+		return store.Settings{}, false
+	}
+	settings, err := h.Storage.FindSettings(c.chatId)
+	if err == nil {
+		err = setting.set(&settings, value)
+	}
+	if err == nil {
+		err = h.Storage.SaveSettings(settings)
+	}
+	if err != nil {
+		h.replyError(c, err)
+		return settings, false
+	}
+	return settings, true
+}
+
+func settingsText(p i18n.Printer, settings store.Settings) string {
+	text := p.T("*Settings:*\n")
+	for _, setting := range chatSettings {
+		text = text + fmt.Sprintf(" `%s` - %s: *%s*\n", setting.key, p.T(setting.description),
+			store.Escape(setting.value(p, settings)))
+	}
+	return text + "\n" + p.T("`/settings capacity 20` changes the setting, `/settings capacity -` restores the default")
+}
+
+func settingsKeyboard(p i18n.Printer, event store.Event, settings store.Settings) tgbotapi.InlineKeyboardMarkup {
+	id := strconv.Itoa(event.Id)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, setting := range chatSettings {
+		if setting.next == nil {
+			continue
+		}
+		text := setting.key + ": " + setting.value(p, settings)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(text, "set:"+id+":"+setting.key)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -1,11 +1,7 @@
 package telegram
 
-import (
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
-)
-
 type BotService struct {
-	Bot     *tgbotapi.BotAPI
+	Source  UpdateSource
 	Handler *MessageHandler
 }
 
@@ -15,36 +11,76 @@ func (s *BotService) Init() {
 		{`add`, ``, h.addMe},
 		{`add`, `^@(\S+)$`, h.addByLink},
 		{`add`, `^\d+$`, h.addByNumber},
+		{`add`, `^\+(\d+)(?:\s+(.+))?$`, h.addGuests},
 		{`add`, `^.+$`, h.addByName},
 		{`rm`, ``, h.removeMe},
 		{`rm`, `^@(\S+)$`, h.removeByLink},
-		{`rm`, `^\d+$`, h.removeByNumber},
+		{`rm`, `^[wW]?\d+$`, h.removeByNumber},
 		{`rm`, `^.+$`, h.removeByName},
+		{`maybe`, ``, h.maybe},
+		{`no`, ``, h.decline},
 		{`list`, ``, h.list},
+		{`pin`, ``, h.pin},
+		{`unpin`, ``, h.unpin},
+		{`event`, ``, h.eventList},
+		{`event`, `^list$`, h.eventList},
+		{`event`, `^new\s+(.+)$`, h.eventNew},
+		{`event`, `^switch\s+(\d+)$`, h.eventSwitchByNumber},
+		{`event`, `^switch\s+(.+)$`, h.eventSwitchByTitle},
+		{`title`, `^.+$`, h.setTitle},
+		{`when`, `^.+$`, h.setStart},
+		{`where`, `^.+$`, h.setLocation},
+		{`about`, `^(?s).+$`, h.setDescription},
+		{`capacity`, `^(\d+|-)$`, h.setCapacity},
+		{`repeat`, `^-$`, h.setRecurrence},
+		{`remind`, `^.+$`, h.setReminders},
+		{`repeat`, `^(?i)([a-z]+)\s+(\d{1,2}):(\d{2})(?:\s+(\S+))?$`, h.setRecurrence},
 		{`ping`, ``, h.ping},
 		{`reset`, ``, h.reset},
+		{`undo`, ``, h.undo},
+		{`history`, ``, h.history},
+		{`history`, `^\d+$`, h.history},
+		{`stats`, ``, h.stats},
+		{`export`, ``, h.export},
+		{`admin`, ``, h.adminCommands},
+		{`admin`, `^.+$`, h.setAdminCommands},
+		{`lang`, ``, h.language},
+		{`lang`, `^\S+$`, h.setLanguage},
+		{`settings`, ``, h.showSettings},
+		{`settings`, `^(\S+)\s+(.+)$`, h.setSetting},
 		{`start`, ``, h.help},
 		{`help`, ``, h.help},
 	}
+	h.callbacks = map[string]func(c conversation){
+		`join`:   h.addMe,
+		`maybe`:  h.maybe,
+		`leave`:  h.removeMe,
+		`reset`:  h.resetConfirmed,
+		`cancel`: h.resetCancelled,
+		`set`:    h.toggleSetting,
+	}
 }
 
 func (s *BotService) Run() error {
 
-	updates, err := s.chatUpdates()
+	updates, errs, err := s.Source.Updates()
 	if err != nil {
 		return err
 	}
 
-	for update := range updates {
-		s.Handler.handle(update.Message)
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if update.CallbackQuery != nil {
+				s.Handler.handleCallback(update.CallbackQuery)
+				continue
+			}
+			s.Handler.handle(update.Message)
+		}
 	}
-	return nil
-}
-
-func (s *BotService) chatUpdates() (tgbotapi.UpdatesChannel, error) {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-
-	updates, err := s.Bot.GetUpdatesChan(u)
-	return updates, err
 }
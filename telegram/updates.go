@@ -0,0 +1,92 @@
+package telegram
+
+import (
+	"crypto/subtle"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/pkg/errors"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// UpdateSource delivers the Telegram updates to the bot service
+type UpdateSource interface {
+	Updates() (tgbotapi.UpdatesChannel, <-chan error, error)
+}
+
+// Polling receives the updates by long polling
+type Polling struct {
+	Bot *tgbotapi.BotAPI
+}
+
+func (s *Polling) Updates() (tgbotapi.UpdatesChannel, <-chan error, error) {
+	// Telegram does not return updates while a webhook is set
+	if _, err := s.Bot.RemoveWebhook(); err != nil {
+		return nil, nil, err
+	}
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+
+	updates, err := s.Bot.GetUpdatesChan(u)
+	return updates, nil, err
+}
+
+// Webhook receives the updates sent by Telegram to the public url
+type Webhook struct {
+	Bot       *tgbotapi.BotAPI
+	Listen    string
+	PublicURL string
+	Secret    string
+}
+
+// Updates registers the webhook and serves it. The errors
+// of the server are sent to the returned channel
+func (s *Webhook) Updates() (tgbotapi.UpdatesChannel, <-chan error, error) {
+	if s.Secret == "" {
+		return nil, nil, errors.New("webhook secret is required")
+	}
+	publicURL, err := url.Parse(s.PublicURL)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "wrong public url %s", s.PublicURL)
+	}
+
+	params := url.Values{}
+	params.Set("url", publicURL.String())
+	params.Set("secret_token", s.Secret)
+	if _, err = s.Bot.MakeRequest("setWebhook", params); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to set webhook")
+	}
+	log.Printf("Webhook is set to %s", publicURL)
+
+	path := publicURL.Path
+	if path == "" {
+		path = "/"
+	}
+	// ListenForWebhook registers the handler in the default mux
+	updates := s.Bot.ListenForWebhook(path)
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Webhook is listening on %s", s.Listen)
+		errs <- http.ListenAndServe(s.Listen, secretTokenHandler(s.Secret, http.DefaultServeMux))
+	}()
+
+	return updates, errs, nil
+}
+
+// secretTokenHandler rejects the requests without the secret token set in the webhook,
+// all of them if the secret is empty
+func secretTokenHandler(secret string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(secretTokenHeader)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			log.Printf("Webhook request with wrong secret token from %s", r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,31 @@
+package telegram
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecretTokenHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tc := range []struct {
+		secret string
+		token  string
+		status int
+	}{
+		{"secret", "secret", http.StatusOK},
+		{"secret", "", http.StatusForbidden},
+		{"secret", "wrong", http.StatusForbidden},
+		{"", "", http.StatusForbidden},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/tbot", nil)
+		if tc.token != "" {
+			r.Header.Set(secretTokenHeader, tc.token)
+		}
+		w := httptest.NewRecorder()
+		secretTokenHandler(tc.secret, next).ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("secret %q, token %q: status %d, want %d", tc.secret, tc.token, w.Code, tc.status)
+		}
+	}
+}